@@ -0,0 +1,199 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// APIKeyIn is where an API key is sent.
+type APIKeyIn int
+
+const (
+	// APIKeyInHeader sends the API key as a header.
+	APIKeyInHeader APIKeyIn = iota
+	// APIKeyInQuery sends the API key as a query parameter.
+	APIKeyInQuery
+)
+
+// credentials authenticate a request by a header or a query parameter,
+// or by an authenticator setting the header.
+type credentials struct {
+	header string
+	query  string
+	value  string
+	auth   authenticator // Sets the header, instead of value.
+}
+
+// authenticator authenticates the exchanges of a request, e.g. answering
+// the challenge of the server.
+type authenticator interface {
+	// do execute req by send, authenticated.
+	do(send Handler, req *http.Request) (*http.Response, error)
+}
+
+func basicAuth(username, password string) *credentials {
+	token := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return &credentials{header: "Authorization", value: "Basic " + token}
+}
+
+func bearerToken(token string) *credentials {
+	return &credentials{header: "Authorization", value: "Bearer " + token}
+}
+
+func apiKey(name, value string, in APIKeyIn) *credentials {
+	if in == APIKeyInQuery {
+		return &credentials{query: name, value: value}
+	}
+	return &credentials{header: name, value: value}
+}
+
+// WithBasicAuth set the default credentials of every request
+// to the basic authentication of username and password.
+func WithBasicAuth(username, password string) Option {
+	return func(ops *options) {
+		ops.credentials = basicAuth(username, password)
+	}
+}
+
+// WithBearerToken set the default credentials of every request
+// to the bearer token.
+func WithBearerToken(token string) Option {
+	return func(ops *options) {
+		ops.credentials = bearerToken(token)
+	}
+}
+
+// WithAPIKey set the default credentials of every request to the API key
+// name, sent as a header or a query parameter.
+func WithAPIKey(name, value string, in APIKeyIn) Option {
+	return func(ops *options) {
+		ops.credentials = apiKey(name, value, in)
+	}
+}
+
+// BasicAuth set the credentials of the request to the basic authentication
+// of username and password, replacing the client ones.
+func (r *Request) BasicAuth(username, password string) *Request {
+	return r.setCredentials(basicAuth(username, password))
+}
+
+// BearerToken set the credentials of the request to the bearer token,
+// replacing the client ones.
+func (r *Request) BearerToken(token string) *Request {
+	return r.setCredentials(bearerToken(token))
+}
+
+// APIKey set the credentials of the request to the API key name, sent as
+// a header or a query parameter, replacing the client ones. A query
+// parameter is added after the ones set by QueryParams.
+func (r *Request) APIKey(name, value string, in APIKeyIn) *Request {
+	return r.setCredentials(apiKey(name, value, in))
+}
+
+func (r *Request) setCredentials(creds *credentials) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.credentials = creds
+	return r
+}
+
+// useCredentials returns the credentials of the request, or the client ones.
+func (r *Request) useCredentials() *credentials {
+	if r.credentials != nil {
+		return r.credentials
+	}
+	return r.owner.options.credentials
+}
+
+func (r *Request) useAuthenticator() authenticator {
+	if creds := r.useCredentials(); creds != nil {
+		return creds.auth
+	}
+	return nil
+}
+
+// credentialsKey is the context key of the credentials of a request,
+// read by checkRedirect.
+type credentialsKey struct{}
+
+// useAuth set the credentials header of req, the query parameter is set
+// by useQueryParams, and the header of an authenticator by the call.
+func (r *Request) useAuth(req *http.Request) *http.Request {
+	creds := r.useCredentials()
+	if creds == nil || creds.header == "" {
+		return req
+	}
+
+	if creds.auth == nil {
+		req.Header.Set(creds.header, creds.value)
+	}
+	return req.WithContext(context.WithValue(req.Context(), credentialsKey{}, creds))
+}
+
+// redact returns redactor, masking the credentials too.
+func (creds *credentials) redact(redactor *Redactor) *Redactor {
+	switch {
+	case creds == nil || redactor == nil:
+	case creds.header != "" && !redactor.headers[http.CanonicalHeaderKey(creds.header)]:
+		return redactor.clone().AddHeaders(creds.header)
+	case creds.query != "" && !redactor.params[strings.ToLower(creds.query)]:
+		return redactor.clone().AddParams(creds.query)
+	}
+	return redactor
+}
+
+// checkRedirect stops after 10 redirects like the default policy of
+// http.Client. The credentials header is removed from a redirect to
+// another origin, even a subdomain or another port of the same host,
+// which http.Client only does for the Authorization header of another
+// domain.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	creds, ok := req.Context().Value(credentialsKey{}).(*credentials)
+	if ok && !sameOrigin(req.URL, via[0].URL) {
+		req.Header.Del(creds.header)
+	}
+	return nil
+}
+
+// sameOrigin reports whether a and b have the same scheme, host and port.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		originPort(a) == originPort(b)
+}
+
+func originPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/coolstina/httpclient/test/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameOrigin(t *testing.T) {
+	grids := []struct {
+		a, b     string
+		expected bool
+	}{
+		{a: "http://example.com/a", b: "http://example.com/b?c=d", expected: true},
+		{a: "http://example.com/", b: "http://EXAMPLE.com:80/", expected: true},
+		{a: "https://example.com/", b: "https://example.com:443/", expected: true},
+		{a: "http://example.com/", b: "https://example.com/", expected: false},
+		{a: "http://example.com/", b: "http://example.com:8080/", expected: false},
+		{a: "http://example.com/", b: "http://www.example.com/", expected: false},
+	}
+
+	for _, grid := range grids {
+		a, _ := url.Parse(grid.a)
+		b, _ := url.Parse(grid.b)
+		assert.Equal(t, grid.expected, sameOrigin(a, b), "%s %s", grid.a, grid.b)
+	}
+}
+
+func (suite *RequestSuite) Test_Auth() {
+	grids := []struct {
+		client []Option
+		build  func(req *Request) *Request
+		header http.Header
+		query  string
+	}{
+		{
+			client: []Option{WithBasicAuth("kitty", "secret")},
+			header: http.Header{"Authorization": {"Basic a2l0dHk6c2VjcmV0"}},
+		},
+		{
+			client: []Option{WithBearerToken("client-token")},
+			header: http.Header{"Authorization": {"Bearer client-token"}},
+		},
+		{
+			client: []Option{WithBasicAuth("kitty", "secret")},
+			build: func(req *Request) *Request {
+				return req.BearerToken("request-token")
+			},
+			header: http.Header{"Authorization": {"Bearer request-token"}},
+		},
+		{
+			client: []Option{WithAPIKey("X-API-Key", "client-key", APIKeyInHeader)},
+			header: http.Header{"X-Api-Key": {"client-key"}},
+		},
+		{
+			client: []Option{WithAPIKey("api_key", "client-key", APIKeyInQuery)},
+			build: func(req *Request) *Request {
+				return req.QueryParams(Params{{Key: "id", Value: "1"}})
+			},
+			query: "api_key=client-key&id=1",
+		},
+		{
+			client: []Option{WithBearerToken("client-token")},
+			build: func(req *Request) *Request {
+				return req.APIKey("api_key", "request-key", APIKeyInQuery)
+			},
+			query: "api_key=request-key",
+		},
+		{
+			// Credentials replace an Authorization header.
+			client: []Option{WithHeader("Authorization", "Token 1")},
+			build: func(req *Request) *Request {
+				return req.BasicAuth("kitty", "")
+			},
+			header: http.Header{"Authorization": {"Basic a2l0dHk6"}},
+		},
+	}
+
+	for _, grid := range grids {
+		req := New(append(grid.client, WithBaseURL(suite.server.URL))...).Get("/echo")
+		if grid.build != nil {
+			req = grid.build(req)
+		}
+
+		echo := suite.echo(req.Do())
+		assert.Equal(suite.T(), grid.query, echo.Query)
+		for key, values := range grid.header {
+			assert.Equal(suite.T(), values, echo.Header[key], key)
+		}
+		if grid.header == nil {
+			assert.Empty(suite.T(), echo.Header["Authorization"])
+		}
+	}
+}
+
+func (suite *RequestSuite) Test_Auth_Redirect() {
+	other := httptest.NewServer(server.NewEngine())
+	defer other.Close()
+
+	grids := []struct {
+		to       string
+		expected []string
+	}{
+		{to: "/echo", expected: []string{"client-key"}},
+		{to: suite.server.URL + "/echo", expected: []string{"client-key"}},
+		{to: other.URL + "/echo"},
+		{to: other.URL + "/redirect?to=" + url.QueryEscape(suite.server.URL+"/echo"), expected: []string{"client-key"}},
+	}
+
+	client := New(WithBaseURL(suite.server.URL), WithAPIKey("X-API-Key", "client-key", APIKeyInHeader))
+	for _, grid := range grids {
+		echo := suite.echo(client.Get("/redirect").QueryParams(Params{{Key: "to", Value: grid.to}}).Do())
+		assert.Equal(suite.T(), grid.expected, echo.Header["X-Api-Key"], grid.to)
+	}
+
+	// The Authorization header is removed from another port of the same host too.
+	echo := suite.echo(New().Get(suite.server.URL+"/redirect?to="+url.QueryEscape(other.URL+"/echo")).BasicAuth("kitty", "secret").Do())
+	assert.Empty(suite.T(), echo.Header["Authorization"])
+}
+
+func (suite *RequestSuite) Test_Auth_Redacted() {
+	recorder := &eventRecorder{}
+	client := New(
+		WithBaseURL(suite.server.URL),
+		WithLogger(recorder),
+		WithLogLevel(LevelDebug),
+		WithDebugDump(1024),
+		WithAPIKey("api_key", "secret-key", APIKeyInQuery),
+	)
+
+	_, err := client.Get("/echo").Do()
+	assert.NoError(suite.T(), err)
+	_, err = client.Get("/echo").APIKey("X-Secret", "secret-key", APIKeyInHeader).Debug(true).Do()
+	assert.NoError(suite.T(), err)
+
+	if assert.Len(suite.T(), recorder.events, 4) {
+		assert.Equal(suite.T(), suite.server.URL+"/echo?api_key="+Redacted, recorder.events[0].URL)
+		assert.Contains(suite.T(), recorder.events[2].Dump, "X-Secret: "+Redacted)
+		assert.NotContains(suite.T(), recorder.events[2].Dump, "secret-key")
+		for _, event := range recorder.events {
+			assert.NotContains(suite.T(), event.URL, "secret-key")
+		}
+	}
+
+	// The redactor of the client is unchanged.
+	assert.False(suite.T(), client.options.redactor.params["api_key"])
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+)
+
+var defaultClient *Client
+var once sync.Once
+
+// Client is an HTTP client with its own configuration and connection pool.
+// Clients created by New are independent of each other and of the
+// default client used by the package-level functions. The options of a
+// Client are fixed by New, so they are read without locking, only the
+// middlewares added by Use are guarded. A Client is safe for concurrent use.
+type Client struct {
+	client      *http.Client
+	options     *options
+	middlewares []Middleware
+	mux         sync.RWMutex
+
+	insecure     *http.Client // Created by insecureClient.
+	insecureOnce sync.Once
+}
+
+// New create a Client configured by the given options.
+func New(opts ...Option) *Client {
+	ops := newOptions(opts...)
+
+	transport := ops.transport
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if t, ok := transport.(*http.Transport); ok && ops.proxy != nil {
+		t = t.Clone()
+		t.Proxy = ops.proxy
+		transport = t
+	}
+
+	return &Client{
+		client: &http.Client{
+			Transport:     transport,
+			Jar:           ops.jar,
+			CheckRedirect: checkRedirect,
+		},
+		options: ops,
+	}
+}
+
+// insecureClient returns a copy of the http.Client which does not verify
+// the server certificates, created on first use and shared by the requests
+// of InsecureSkipVerify, so they reuse its connections. Its transport is a
+// copy of the client one, keeping its proxy and TLS configuration, which
+// InsecureSkipVerify checks is an *http.Transport.
+func (c *Client) insecureClient() *http.Client {
+	c.insecureOnce.Do(func() {
+		transport := c.client.Transport.(*http.Transport).Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = true
+
+		client := *c.client
+		client.Transport = transport
+		c.insecure = &client
+	})
+	return c.insecure
+}
+
+// NewHttpClientOr returns the default Client, creating it on first use.
+// The package-level Get and Post are issued through it.
+func NewHttpClientOr() *Client {
+	once.Do(func() {
+		defaultClient = New()
+	})
+	return defaultClient
+}
+
+// Method returns a new request of the given verb executed by the client,
+// it is used for verbs without a dedicated function, e.g. PROPFIND.
+func (c *Client) Method(verb string, url string) *Request {
+	return newRequest(c, verb, url)
+}
+
+// Get returns a new GET request executed by the client.
+func (c *Client) Get(url string) *Request {
+	return newRequest(c, http.MethodGet, url)
+}
+
+// Post returns a new POST request executed by the client.
+func (c *Client) Post(url string) *Request {
+	return newRequest(c, http.MethodPost, url)
+}
+
+// Put returns a new PUT request executed by the client.
+func (c *Client) Put(url string) *Request {
+	return newRequest(c, http.MethodPut, url)
+}
+
+// Patch returns a new PATCH request executed by the client.
+func (c *Client) Patch(url string) *Request {
+	return newRequest(c, http.MethodPatch, url)
+}
+
+// Delete returns a new DELETE request executed by the client.
+func (c *Client) Delete(url string) *Request {
+	return newRequest(c, http.MethodDelete, url)
+}
+
+// Head returns a new HEAD request executed by the client,
+// the body of a HEAD request is never read.
+func (c *Client) Head(url string) *Request {
+	return newRequest(c, http.MethodHead, url)
+}
+
+// Options returns a new OPTIONS request executed by the client.
+func (c *Client) Options(url string) *Request {
+	return newRequest(c, http.MethodOptions, url)
+}
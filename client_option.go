@@ -0,0 +1,106 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"net/http"
+	"time"
+)
+
+type options struct {
+	baseURL   string
+	header    http.Header
+	userAgent string
+	timeout   time.Duration
+	transport http.RoundTripper
+	retry     *RetryPolicy
+	jar       http.CookieJar
+	proxy     ProxyFunc
+
+	credentials *credentials
+
+	logger      Logger
+	logLevel    Level
+	dump        bool
+	dumpMaxBody int
+	redactor    *Redactor
+}
+
+// Option is used to configure a Client.
+type Option func(ops *options)
+
+// WithBaseURL set the base URL, relative request URLs are joined to it.
+func WithBaseURL(url string) Option {
+	return func(ops *options) {
+		ops.baseURL = url
+	}
+}
+
+// WithHeader add a default header, which is sent with every request.
+func WithHeader(key, value string) Option {
+	return func(ops *options) {
+		ops.header.Add(key, value)
+	}
+}
+
+// WithHeaders add default headers, which are sent with every request.
+func WithHeaders(header http.Header) Option {
+	return func(ops *options) {
+		for key, values := range header {
+			for _, value := range values {
+				ops.header.Add(key, value)
+			}
+		}
+	}
+}
+
+// WithUserAgent set the default User-Agent header, which is
+// DefaultUserAgent unless configured.
+func WithUserAgent(userAgent string) Option {
+	return func(ops *options) {
+		ops.userAgent = userAgent
+	}
+}
+
+// WithTimeout set the default timeout of every request,
+// a request can override it by calling Timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(ops *options) {
+		ops.timeout = timeout
+	}
+}
+
+// WithTransport set the transport used to execute requests.
+// By default each Client uses its own copy of http.DefaultTransport,
+// so clients never share a connection pool.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(ops *options) {
+		ops.transport = transport
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	ops := &options{
+		header:    make(http.Header),
+		userAgent: DefaultUserAgent,
+		logger:    NewStdLogger(nil),
+		logLevel:  LevelOff,
+		redactor:  NewRedactor(),
+	}
+	for _, opt := range opts {
+		opt(ops)
+	}
+	return ops
+}
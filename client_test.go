@@ -0,0 +1,170 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coolstina/httpclient/test/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// echoSuite serves the mock API of test/server for the embedding suite.
+type echoSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+func (suite *echoSuite) SetupSuite() {
+	suite.server = httptest.NewServer(server.NewEngine())
+}
+
+func (suite *echoSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *echoSuite) echo(resp *Response, err error) *server.EchoResponse {
+	var echo server.EchoResponse
+	if assert.NoError(suite.T(), err) {
+		assert.NoError(suite.T(), resp.JSON(&echo))
+	}
+	return &echo
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
+
+type ClientSuite struct {
+	echoSuite
+}
+
+func (suite *ClientSuite) Test_New_Independent() {
+	first := New(WithBaseURL(suite.server.URL), WithHeader("X-Client", "first"))
+	second := New(WithBaseURL(suite.server.URL), WithHeader("X-Client", "second"))
+
+	assert.NotSame(suite.T(), first.client, second.client)
+	assert.NotSame(suite.T(), first.client.Transport, second.client.Transport)
+
+	echo := suite.echo(first.Get("/echo").Do())
+	assert.Equal(suite.T(), []string{"first"}, echo.Header["X-Client"])
+
+	echo = suite.echo(second.Post("/echo").BodyWithJSON(`{}`).Do())
+	assert.Equal(suite.T(), http.MethodPost, echo.Method)
+	assert.Equal(suite.T(), []string{"second"}, echo.Header["X-Client"])
+}
+
+func (suite *ClientSuite) Test_WithBaseURL() {
+	cli := New(WithBaseURL(suite.server.URL + "/"))
+
+	echo := suite.echo(cli.Get("echo").QueryParams(Params{{Key: "id", Value: "1"}}).Do())
+	assert.Equal(suite.T(), "/echo", echo.Path)
+	assert.Equal(suite.T(), "id=1", echo.Query)
+
+	// Absolute URLs ignore the base URL.
+	echo = suite.echo(cli.Get(suite.server.URL + "/echo").Do())
+	assert.Equal(suite.T(), "/echo", echo.Path)
+}
+
+func (suite *ClientSuite) Test_WithHeaders() {
+	header := http.Header{}
+	header.Add("X-Tag", "a")
+	header.Add("X-Tag", "b")
+	cli := New(WithHeaders(header))
+
+	echo := suite.echo(cli.Get(suite.server.URL + "/echo").Do())
+	assert.Equal(suite.T(), []string{"a", "b"}, echo.Header["X-Tag"])
+}
+
+func (suite *ClientSuite) Test_WithTimeout() {
+	var deadline bool
+	cli := New(
+		WithTimeout(time.Second),
+		WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			_, deadline = req.Context().Deadline()
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		})),
+	)
+
+	resp, err := cli.Get("http://localhost/").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.True(suite.T(), deadline)
+}
+
+func (suite *ClientSuite) Test_WithLogger() {
+	var output strings.Builder
+	cli := New(WithLogger(NewStdLogger(log.New(&output, "", 0))))
+
+	_, err := cli.Get(suite.server.URL + "/echo").Debug(true).Do()
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), output.String(), suite.server.URL+"/echo")
+}
+
+func (suite *ClientSuite) Test_Methods() {
+	cli := New(WithBaseURL(suite.server.URL))
+
+	grids := []struct {
+		request  *Request
+		expected string
+	}{
+		{request: cli.Get("/echo"), expected: http.MethodGet},
+		{request: cli.Post("/echo"), expected: http.MethodPost},
+		{request: cli.Put("/echo"), expected: http.MethodPut},
+		{request: cli.Patch("/echo"), expected: http.MethodPatch},
+		{request: cli.Delete("/echo"), expected: http.MethodDelete},
+		{request: cli.Options("/echo"), expected: http.MethodOptions},
+		{request: cli.Method("PROPFIND", "/echo"), expected: "PROPFIND"},
+		{request: Put(suite.server.URL + "/echo"), expected: http.MethodPut},
+		{request: Method("PROPFIND", suite.server.URL+"/echo"), expected: "PROPFIND"},
+	}
+
+	for _, grid := range grids {
+		echo := suite.echo(grid.request.Body(strings.NewReader("body")).Do())
+		assert.Equal(suite.T(), grid.expected, echo.Method)
+		assert.Equal(suite.T(), "body", echo.Body)
+	}
+}
+
+type unreadable struct{}
+
+func (unreadable) Read(p []byte) (int, error) {
+	panic("the body of a HEAD request must not be read")
+}
+
+func (suite *ClientSuite) Test_Head() {
+	resp, err := New().Head(suite.server.URL + "/echo").Body(unreadable{}).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), http.NoBody, resp.Body)
+}
+
+func (suite *ClientSuite) Test_NewHttpClientOr() {
+	assert.Same(suite.T(), NewHttpClientOr(), NewHttpClientOr())
+	assert.NotSame(suite.T(), NewHttpClientOr(), New())
+}
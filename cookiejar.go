@@ -0,0 +1,595 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+var errCookieDomain = errors.New("httpclient: invalid cookie domain")
+
+// WithCookieJar set the cookie jar of the client, which stores the cookies
+// of the responses and sends them with the requests. By default a Client
+// has no cookie jar, see NewJar.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(ops *options) {
+		ops.jar = jar
+	}
+}
+
+// Jar is an http.CookieJar following RFC 6265, which rejects the cookies
+// set for a public suffix like "com" or "co.uk".
+//
+// Unlike net/http/cookiejar, the cookies of a Jar can be inspected and
+// cleared per domain, and saved to a JSON file to keep a session across
+// runs. A Jar is safe for concurrent use.
+type Jar struct {
+	// Cookies are grouped by the registrable domain (eTLD+1) of their
+	// domain, then keyed by their domain, path and name.
+	entries map[string]map[string]*jarEntry
+	seq     uint64 // Orders the cookies of the same creation time.
+	mux     sync.Mutex
+}
+
+// jarEntry is a cookie stored by a Jar, as saved to the JSON file.
+type jarEntry struct {
+	Name       string    `json:"name"`
+	Value      string    `json:"value"`
+	Domain     string    `json:"domain"`
+	Path       string    `json:"path"`
+	HostOnly   bool      `json:"host_only"`
+	Secure     bool      `json:"secure"`
+	HttpOnly   bool      `json:"http_only"`
+	SameSite   string    `json:"same_site,omitempty"`
+	Persistent bool      `json:"persistent"`
+	Expires    time.Time `json:"expires"`
+	Creation   time.Time `json:"creation"`
+	seq        uint64
+}
+
+// NewJar returns an empty Jar.
+func NewJar() *Jar {
+	return &Jar{entries: make(map[string]map[string]*jarEntry)}
+}
+
+// SetCookies implements the http.CookieJar interface, it stores the
+// cookies of a response from u.
+func (jar *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	path := defaultCookiePath(u.Path)
+
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	for _, cookie := range cookies {
+		entry, remove, err := newJarEntry(cookie, host, path, u.Scheme == "https", now)
+		if err != nil {
+			continue
+		}
+
+		if remove {
+			jar.remove(entry)
+			continue
+		}
+		jar.store(entry)
+	}
+}
+
+// Cookies implements the http.CookieJar interface, it returns the cookies
+// to send in a request to u, the ones of the longest paths first.
+func (jar *Jar) Cookies(u *url.URL) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	key := registrableDomain(host)
+	var selected []*jarEntry
+	for id, entry := range jar.entries[key] {
+		if entry.expired(now) {
+			delete(jar.entries[key], id)
+			continue
+		}
+		if entry.shouldSend(u.Scheme == "https", host, path) {
+			selected = append(selected, entry)
+		}
+	}
+	if len(jar.entries[key]) == 0 {
+		delete(jar.entries, key)
+	}
+
+	// RFC 6265 section 5.4, longer paths first, then earlier creation times.
+	sort.Slice(selected, func(i, j int) bool {
+		if len(selected[i].Path) != len(selected[j].Path) {
+			return len(selected[i].Path) > len(selected[j].Path)
+		}
+		if !selected[i].Creation.Equal(selected[j].Creation) {
+			return selected[i].Creation.Before(selected[j].Creation)
+		}
+		return selected[i].seq < selected[j].seq
+	})
+
+	cookies := make([]*http.Cookie, 0, len(selected))
+	for _, entry := range selected {
+		cookies = append(cookies, &http.Cookie{Name: entry.Name, Value: entry.Value})
+	}
+	return cookies
+}
+
+// Domains returns the domains having cookies, sorted.
+func (jar *Jar) Domains() []string {
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	seen := make(map[string]bool)
+	domains := make([]string, 0)
+	for _, entries := range jar.entries {
+		for _, entry := range entries {
+			if !seen[entry.Domain] {
+				seen[entry.Domain] = true
+				domains = append(domains, entry.Domain)
+			}
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// DomainCookies returns the cookies of domain and its subdomains,
+// including their attributes, sorted by domain, path and name.
+// Expired cookies are never returned.
+func (jar *Jar) DomainCookies(domain string) []*http.Cookie {
+	domain = canonicalDomain(domain)
+	now := time.Now()
+
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	var selected []*jarEntry
+	for _, entries := range jar.entries {
+		for _, entry := range entries {
+			if entry.inDomain(domain) && !entry.expired(now) {
+				selected = append(selected, entry)
+			}
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].id() < selected[j].id()
+	})
+
+	cookies := make([]*http.Cookie, 0, len(selected))
+	for _, entry := range selected {
+		cookies = append(cookies, entry.cookie())
+	}
+	return cookies
+}
+
+// ClearDomain removes the cookies of domain and its subdomains.
+func (jar *Jar) ClearDomain(domain string) {
+	domain = canonicalDomain(domain)
+
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	for key, entries := range jar.entries {
+		for id, entry := range entries {
+			if entry.inDomain(domain) {
+				delete(entries, id)
+			}
+		}
+		if len(entries) == 0 {
+			delete(jar.entries, key)
+		}
+	}
+}
+
+// Clear removes every cookie.
+func (jar *Jar) Clear() {
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	jar.entries = make(map[string]map[string]*jarEntry)
+}
+
+type jarFile struct {
+	Cookies []*jarEntry `json:"cookies"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. Session cookies
+// are kept along with persistent ones, as a CLI tool usually expects its
+// session to outlive the process, expired cookies are dropped.
+func (jar *Jar) MarshalJSON() ([]byte, error) {
+	now := time.Now()
+
+	jar.mux.Lock()
+	file := jarFile{Cookies: make([]*jarEntry, 0)}
+	for _, entries := range jar.entries {
+		for _, entry := range entries {
+			if !entry.expired(now) {
+				file.Cookies = append(file.Cookies, entry)
+			}
+		}
+	}
+	jar.mux.Unlock()
+
+	sort.Slice(file.Cookies, func(i, j int) bool {
+		return file.Cookies[i].id() < file.Cookies[j].id()
+	})
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, it adds the
+// cookies of data to the jar, replacing the ones of the same domain, path
+// and name. Expired cookies and the ones of invalid domains are skipped,
+// including the domain cookies of a public suffix, like by SetCookies.
+func (jar *Jar) UnmarshalJSON(data []byte) error {
+	var file jarFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	for _, entry := range file.Cookies {
+		entry.Domain = canonicalDomain(entry.Domain)
+		if entry.Domain == "" || entry.Name == "" || entry.expired(now) || entry.publicSuffix() {
+			continue
+		}
+		if entry.Path == "" || entry.Path[0] != '/' {
+			entry.Path = "/"
+		}
+		if entry.Creation.IsZero() {
+			entry.Creation = now
+		}
+		jar.store(entry)
+	}
+	return nil
+}
+
+// Save writes the cookies of the jar to the JSON file of the given path,
+// readable by the owner only, see MarshalJSON. The file is replaced
+// atomically, so a concurrent Load never reads a partial file.
+func (jar *Jar) Save(path string) error {
+	data, err := jar.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Load adds the cookies of the JSON file of the given path written by Save,
+// see UnmarshalJSON. If the file does not exist, the error satisfies
+// errors.Is(err, os.ErrNotExist), so the first run of a tool can ignore it.
+func (jar *Jar) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return jar.UnmarshalJSON(data)
+}
+
+// store add entry, replacing the one of the same id, whose creation time
+// is kept. The lock must be held.
+func (jar *Jar) store(entry *jarEntry) {
+	if jar.entries == nil {
+		jar.entries = make(map[string]map[string]*jarEntry)
+	}
+
+	key := registrableDomain(entry.Domain)
+	entries := jar.entries[key]
+	if entries == nil {
+		entries = make(map[string]*jarEntry)
+		jar.entries[key] = entries
+	}
+
+	id := entry.id()
+	if old, ok := entries[id]; ok {
+		entry.Creation, entry.seq = old.Creation, old.seq
+	} else {
+		jar.seq++
+		entry.seq = jar.seq
+	}
+	entries[id] = entry
+}
+
+// remove delete the entry of the same id as entry. The lock must be held.
+func (jar *Jar) remove(entry *jarEntry) {
+	key := registrableDomain(entry.Domain)
+	if entries, ok := jar.entries[key]; ok {
+		delete(entries, entry.id())
+		if len(entries) == 0 {
+			delete(jar.entries, key)
+		}
+	}
+}
+
+// newJarEntry returns the entry of cookie set by a response from host,
+// and whether the cookie removes the stored one instead.
+func newJarEntry(cookie *http.Cookie, host, path string, https bool, now time.Time) (*jarEntry, bool, error) {
+	if cookie.Name == "" {
+		return nil, false, errors.New("httpclient: empty cookie name")
+	}
+
+	// Secure cookies are only set by secure origins, RFC 6265bis.
+	if cookie.Secure && !https {
+		return nil, false, errors.New("httpclient: secure cookie from an insecure origin")
+	}
+
+	domain, hostOnly, err := cookieDomain(host, cookie.Domain)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry := &jarEntry{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   domain,
+		Path:     path,
+		HostOnly: hostOnly,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: sameSiteString(cookie.SameSite),
+		Creation: now,
+	}
+	if cookie.Path != "" && cookie.Path[0] == '/' {
+		entry.Path = cookie.Path
+	}
+
+	// Max-Age takes precedence over Expires.
+	switch {
+	case cookie.MaxAge < 0:
+		return entry, true, nil
+	case cookie.MaxAge > 0:
+		entry.Persistent = true
+		entry.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	case !cookie.Expires.IsZero():
+		if !cookie.Expires.After(now) {
+			return entry, true, nil
+		}
+		entry.Persistent = true
+		entry.Expires = cookie.Expires
+	}
+	return entry, false, nil
+}
+
+func (entry *jarEntry) id() string {
+	return entry.Domain + ";" + entry.Path + ";" + entry.Name
+}
+
+func (entry *jarEntry) expired(now time.Time) bool {
+	return entry.Persistent && !entry.Expires.After(now)
+}
+
+// inDomain reports whether the cookie belongs to domain or its subdomains.
+func (entry *jarEntry) inDomain(domain string) bool {
+	return entry.Domain == domain || hasDotSuffix(entry.Domain, domain)
+}
+
+// publicSuffix reports whether entry is a domain cookie of a public suffix.
+func (entry *jarEntry) publicSuffix() bool {
+	if entry.HostOnly || net.ParseIP(entry.Domain) != nil {
+		return false
+	}
+	suffix, _ := publicsuffix.PublicSuffix(entry.Domain)
+	return suffix == entry.Domain
+}
+
+// shouldSend reports whether the cookie is sent in a request to host and path.
+func (entry *jarEntry) shouldSend(https bool, host, path string) bool {
+	if entry.Secure && !https {
+		return false
+	}
+
+	if entry.HostOnly {
+		if host != entry.Domain {
+			return false
+		}
+	} else if host != entry.Domain && !hasDotSuffix(host, entry.Domain) {
+		return false
+	}
+
+	// RFC 6265 section 5.1.4, path-match.
+	if path == entry.Path {
+		return true
+	}
+	return strings.HasPrefix(path, entry.Path) &&
+		(entry.Path[len(entry.Path)-1] == '/' || path[len(entry.Path)] == '/')
+}
+
+func (entry *jarEntry) cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     entry.Name,
+		Value:    entry.Value,
+		Domain:   entry.Domain,
+		Path:     entry.Path,
+		Secure:   entry.Secure,
+		HttpOnly: entry.HttpOnly,
+		SameSite: parseSameSite(entry.SameSite),
+	}
+	if entry.Persistent {
+		cookie.Expires = entry.Expires
+	}
+	return cookie
+}
+
+// cookieDomain returns the domain of a cookie set by host with the Domain
+// attribute domain, and whether the cookie is only sent to host.
+func cookieDomain(host, domain string) (string, bool, error) {
+	if domain == "" {
+		return host, true, nil
+	}
+
+	if net.ParseIP(host) != nil {
+		// An IP address only sets host-only cookies.
+		if host != domain {
+			return "", false, errCookieDomain
+		}
+		return host, true, nil
+	}
+
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if domain == "" || strings.HasSuffix(domain, ".") {
+		return "", false, errCookieDomain
+	}
+
+	// RFC 6265 section 5.3 step 5, a cookie of a public suffix is only
+	// accepted by the host of the same name, as a host-only cookie.
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+		if host == domain {
+			return host, true, nil
+		}
+		return "", false, errCookieDomain
+	}
+
+	if host != domain && !hasDotSuffix(host, domain) {
+		return "", false, errCookieDomain
+	}
+	return domain, false, nil
+}
+
+// canonicalHost returns the lowercase ASCII host of a URL, without port.
+func canonicalHost(host string) (string, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if host == "" {
+		return "", errCookieDomain
+	}
+	return idna.ToASCII(strings.ToLower(host))
+}
+
+// canonicalDomain returns the lowercase ASCII form of a cookie domain,
+// or an empty string if it is invalid.
+func canonicalDomain(domain string) string {
+	domain, err := idna.ToASCII(strings.ToLower(strings.Trim(domain, ".")))
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// registrableDomain returns the eTLD+1 of host, or host itself if it
+// is an IP address or a public suffix.
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// defaultCookiePath returns the default path of a cookie set by a response
+// to path, RFC 6265 section 5.1.4.
+func defaultCookiePath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+func hasDotSuffix(s, suffix string) bool {
+	return len(s) > len(suffix) && s[len(s)-len(suffix)-1] == '.' && s[len(s)-len(suffix):] == suffix
+}
+
+func sameSiteString(sameSite http.SameSite) string {
+	switch sameSite {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+func parseSameSite(sameSite string) http.SameSite {
+	switch strings.ToLower(sameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return 0
+}
+
+// writeFileAtomic replaces the file of the given path by data, readable by
+// the owner only. data is written to a temporary file renamed to path, so
+// a concurrent reader never reads a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpOnlyPrefix prefixes the domain of the HttpOnly cookies of a
+// Netscape cookies.txt file, like curl does.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// ImportNetscape adds the cookies of a Netscape cookies.txt file, the
+// format used by curl, wget and browser extensions, replacing the ones of
+// the same domain, path and name. An expiry of 0 is a session cookie.
+// Expired cookies are skipped, and so are the domain cookies of a public
+// suffix, which SetCookies rejects. A malformed line fails the import.
+func (jar *Jar) ImportNetscape(r io.Reader) error {
+	now := time.Now()
+	var entries []*jarEntry
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(text, httpOnlyPrefix)
+		if httpOnly {
+			text = text[len(httpOnlyPrefix):]
+		}
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		entry, err := parseNetscapeLine(text, now)
+		if err != nil {
+			return fmt.Errorf("httpclient: cookies.txt line %d: %w", line, err)
+		}
+		entry.HttpOnly = httpOnly
+		if !entry.expired(now) && !entry.publicSuffix() {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	jar.mux.Lock()
+	defer jar.mux.Unlock()
+
+	for _, entry := range entries {
+		jar.store(entry)
+	}
+	return nil
+}
+
+// parseNetscapeLine parses the tab separated fields of a cookie: domain,
+// include subdomains, path, secure, expiry, name and value.
+func parseNetscapeLine(text string, now time.Time) (*jarEntry, error) {
+	fields := strings.Split(text, "\t")
+	if len(fields) == 6 {
+		// Some exporters drop the separator of an empty value.
+		fields = append(fields, "")
+	}
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("%d fields, want 7", len(fields))
+	}
+
+	includeSubdomains, err := parseNetscapeBool(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	secure, err := parseNetscapeBool(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry %q", fields[4])
+	}
+
+	entry := &jarEntry{
+		Name:     fields[5],
+		Value:    fields[6],
+		Domain:   canonicalDomain(fields[0]),
+		Path:     fields[2],
+		HostOnly: !includeSubdomains,
+		Secure:   secure,
+		Creation: now,
+	}
+	if entry.Domain == "" {
+		return nil, fmt.Errorf("invalid domain %q", fields[0])
+	}
+	if entry.Name == "" {
+		return nil, fmt.Errorf("empty cookie name")
+	}
+	if entry.Path == "" || entry.Path[0] != '/' {
+		entry.Path = "/"
+	}
+	if expiry > 0 {
+		entry.Persistent = true
+		entry.Expires = time.Unix(expiry, 0)
+	}
+	return entry, nil
+}
+
+func parseNetscapeBool(value string) (bool, error) {
+	switch strings.ToUpper(value) {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", value)
+}
+
+// ExportNetscape writes the cookies of the jar as a Netscape cookies.txt
+// file, which curl and wget read. HttpOnly cookies are prefixed by
+// #HttpOnly_, session cookies have an expiry of 0, and expired cookies
+// are dropped.
+func (jar *Jar) ExportNetscape(w io.Writer) error {
+	now := time.Now()
+
+	jar.mux.Lock()
+	var entries []*jarEntry
+	for _, domainEntries := range jar.entries {
+		for _, entry := range domainEntries {
+			if !entry.expired(now) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	jar.mux.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].id() < entries[j].id()
+	})
+
+	writer := bufio.NewWriter(w)
+	writer.WriteString("# Netscape HTTP Cookie File\n")
+	writer.WriteString("# Generated by " + DefaultUserAgent + ", edit at your own risk.\n\n")
+	for _, entry := range entries {
+		domain, includeSubdomains := entry.Domain, "FALSE"
+		if !entry.HostOnly {
+			domain, includeSubdomains = "."+entry.Domain, "TRUE"
+		}
+		if entry.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+
+		var expiry int64
+		if entry.Persistent {
+			expiry = entry.Expires.Unix()
+		}
+
+		secure := "FALSE"
+		if entry.Secure {
+			secure = "TRUE"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, includeSubdomains, entry.Path, secure, expiry, entry.Name, entry.Value)
+	}
+	return writer.Flush()
+}
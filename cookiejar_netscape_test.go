@@ -0,0 +1,135 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJar_ImportNetscape(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	file := fmt.Sprintf("# Netscape HTTP Cookie File\n"+
+		"# https://curl.se/docs/http-cookies.html\n"+
+		"\n"+
+		".example.com\tTRUE\t/\tFALSE\t%d\tdomain\t1\n"+
+		"www.example.com\tFALSE\t/admin\tTRUE\t0\tsecure\t2\r\n"+
+		"#HttpOnly_www.example.com\tFALSE\t/\tFALSE\t%[1]d\thttponly\t3\n"+
+		".example.com\tTRUE\t/\tFALSE\t1\texpired\t4\n"+
+		".co.uk\tTRUE\t/\tFALSE\t0\tsuffix\t5\n"+
+		"www.example.com\tFALSE\t/\tFALSE\t0\tempty\n", expires)
+
+	jar := NewJar()
+	assert.NoError(t, jar.ImportNetscape(strings.NewReader(file)))
+
+	grids := []struct {
+		url      string
+		expected []string
+	}{
+		{url: "https://www.example.com/admin", expected: []string{"secure=2", "domain=1", "httponly=3", "empty="}},
+		{url: "http://www.example.com/admin", expected: []string{"domain=1", "httponly=3", "empty="}},
+		{url: "http://api.example.com/", expected: []string{"domain=1"}},
+		{url: "http://www.example.co.uk/", expected: []string{}},
+	}
+
+	for _, grid := range grids {
+		u, _ := url.Parse(grid.url)
+		assert.Equal(t, grid.expected, cookieNames(jar.Cookies(u)), grid.url)
+	}
+
+	cookies := jar.DomainCookies("www.example.com")
+	if assert.Len(t, cookies, 3) {
+		assert.Equal(t, "httponly", cookies[1].Name)
+		assert.True(t, cookies[1].HttpOnly)
+		assert.Equal(t, expires, cookies[1].Expires.Unix())
+		assert.Equal(t, "secure", cookies[2].Name)
+		assert.True(t, cookies[2].Secure)
+		assert.True(t, cookies[2].Expires.IsZero())
+	}
+
+	// The cookie of a public suffix is skipped, not the whole file.
+	assert.Equal(t, []string{"example.com", "www.example.com"}, jar.Domains())
+}
+
+func TestJar_ImportNetscape_Invalid(t *testing.T) {
+	grids := []struct {
+		file string
+		err  string
+	}{
+		{file: "example.com\tTRUE\t/\n", err: "line 1: 3 fields, want 7"},
+		{file: "# comment\nexample.com\tYES\t/\tFALSE\t0\tname\tvalue\n", err: `line 2: invalid boolean "YES"`},
+		{file: "example.com\tTRUE\t/\tFALSE\tnever\tname\tvalue\n", err: `line 1: invalid expiry "never"`},
+		{file: "example.com\tTRUE\t/\tFALSE\t0\t\tvalue\n", err: "line 1: empty cookie name"},
+	}
+
+	for _, grid := range grids {
+		jar := NewJar()
+		err := jar.ImportNetscape(strings.NewReader(grid.file))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), grid.err)
+		}
+		assert.Empty(t, jar.Domains())
+	}
+}
+
+func TestJar_ExportNetscape(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	jar := NewJar()
+	u, _ := url.Parse("https://www.example.com/admin/login")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "1", HttpOnly: true},
+		{Name: "remember", Value: "2", Domain: "example.com", Path: "/", Expires: expires, Secure: true},
+	})
+
+	var buf bytes.Buffer
+	assert.NoError(t, jar.ExportNetscape(&buf))
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "# Netscape HTTP Cookie File", lines[0])
+	assert.Equal(t, []string{
+		fmt.Sprintf(".example.com\tTRUE\t/\tTRUE\t%d\tremember\t2", expires.Unix()),
+		"#HttpOnly_www.example.com\tFALSE\t/admin\tFALSE\t0\tsession\t1",
+		"",
+	}, lines[3:])
+
+	imported := NewJar()
+	assert.NoError(t, imported.ImportNetscape(&buf))
+	assert.Equal(t, cookieNames(jar.Cookies(u)), cookieNames(imported.Cookies(u)))
+	assert.Equal(t, jar.Domains(), imported.Domains())
+}
+
+func (suite *CookieJarSuite) Test_ImportNetscape() {
+	u, _ := url.Parse(suite.server.URL)
+	file := fmt.Sprintf("#HttpOnly_%s\tFALSE\t/\tFALSE\t0\tsession\tkitty\n", u.Hostname())
+
+	jar := NewJar()
+	assert.NoError(suite.T(), jar.ImportNetscape(strings.NewReader(file)))
+	client := New(WithBaseURL(suite.server.URL), WithCookieJar(jar))
+
+	var session struct{ Username string }
+	resp, err := client.Get("/session").Do()
+	if assert.NoError(suite.T(), err) {
+		assert.NoError(suite.T(), resp.JSON(&session))
+		assert.Equal(suite.T(), "kitty", session.Username)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func mustParseURL(t *testing.T, rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func cookieNames(cookies []*http.Cookie) []string {
+	names := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
+		names = append(names, cookie.Name+"="+cookie.Value)
+	}
+	return names
+}
+
+func TestCookieDomain(t *testing.T) {
+	grids := []struct {
+		host     string
+		domain   string
+		expected string
+		hostOnly bool
+		err      bool
+	}{
+		{host: "www.example.com", domain: "", expected: "www.example.com", hostOnly: true},
+		{host: "www.example.com", domain: "example.com", expected: "example.com"},
+		{host: "www.example.com", domain: ".EXAMPLE.com", expected: "example.com"},
+		{host: "www.example.com", domain: "www.example.com", expected: "www.example.com"},
+		{host: "www.example.com", domain: "other.com", err: true},
+		{host: "www.example.com", domain: "ample.com", err: true},
+		{host: "www.example.com", domain: "com", err: true},
+		{host: "www.example.co.uk", domain: "co.uk", err: true},
+		{host: "co.uk", domain: "co.uk", expected: "co.uk", hostOnly: true},
+		{host: "127.0.0.1", domain: "127.0.0.1", expected: "127.0.0.1", hostOnly: true},
+		{host: "127.0.0.1", domain: "0.0.1", err: true},
+		{host: "www.example.com", domain: "example.com.", err: true},
+	}
+
+	for _, grid := range grids {
+		domain, hostOnly, err := cookieDomain(grid.host, grid.domain)
+		if grid.err {
+			assert.Error(t, err, grid.domain)
+			continue
+		}
+		assert.NoError(t, err, grid.domain)
+		assert.Equal(t, grid.expected, domain, grid.domain)
+		assert.Equal(t, grid.hostOnly, hostOnly, grid.domain)
+	}
+}
+
+func TestDefaultCookiePath(t *testing.T) {
+	grids := []struct {
+		path     string
+		expected string
+	}{
+		{path: "", expected: "/"},
+		{path: "/", expected: "/"},
+		{path: "/login", expected: "/"},
+		{path: "/admin/login", expected: "/admin"},
+		{path: "/admin/", expected: "/admin"},
+		{path: "login", expected: "/"},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, defaultCookiePath(grid.path), grid.path)
+	}
+}
+
+func TestJar_Cookies(t *testing.T) {
+	jar := NewJar()
+	jar.SetCookies(mustParseURL(t, "https://www.example.com/admin/login"), []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: "example.com", Path: "/"},
+		{Name: "admin", Value: "3", Path: "/admin/users"},
+		{Name: "secure", Value: "4", Path: "/", Secure: true},
+		{Name: "suffix", Value: "5", Domain: "com"},
+	})
+	jar.SetCookies(mustParseURL(t, "http://www.example.com/"), []*http.Cookie{
+		{Name: "insecure", Value: "6", Secure: true},
+	})
+
+	grids := []struct {
+		url      string
+		expected []string
+	}{
+		{url: "https://www.example.com/admin", expected: []string{"host=1", "domain=2", "secure=4"}},
+		{url: "https://www.example.com/admin/users/1", expected: []string{"admin=3", "host=1", "domain=2", "secure=4"}},
+		{url: "https://www.example.com/administrator", expected: []string{"domain=2", "secure=4"}},
+		{url: "http://www.example.com:8080/admin", expected: []string{"host=1", "domain=2"}},
+		{url: "https://api.example.com/", expected: []string{"domain=2"}},
+		{url: "https://example.com/", expected: []string{"domain=2"}},
+		{url: "https://other.com/", expected: []string{}},
+		{url: "ftp://www.example.com/", expected: []string{}},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, cookieNames(jar.Cookies(mustParseURL(t, grid.url))), grid.url)
+	}
+}
+
+func TestJar_Expiry(t *testing.T) {
+	u := mustParseURL(t, "http://example.com/")
+
+	jar := NewJar()
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "1"},
+		{Name: "max_age", Value: "2", MaxAge: 3600},
+		{Name: "expires", Value: "3", Expires: time.Now().Add(time.Hour)},
+		{Name: "expired", Value: "4", Expires: time.Now().Add(-time.Hour)},
+	})
+	assert.Equal(t, []string{"session=1", "max_age=2", "expires=3"}, cookieNames(jar.Cookies(u)))
+
+	// Max-Age takes precedence over Expires.
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "max_age", MaxAge: -1},
+		{Name: "expires", Expires: time.Now().Add(-time.Hour)},
+		{Name: "session", Value: "5", MaxAge: 1, Expires: time.Now().Add(-time.Hour)},
+	})
+	assert.Equal(t, []string{"session=5"}, cookieNames(jar.Cookies(u)))
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.Empty(t, jar.Cookies(u))
+	assert.Empty(t, jar.Domains())
+}
+
+func TestJar_DomainCookies(t *testing.T) {
+	jar := NewJar()
+	jar.SetCookies(mustParseURL(t, "https://www.example.com/"), []*http.Cookie{
+		{Name: "host", Value: "1", HttpOnly: true, SameSite: http.SameSiteLaxMode},
+		{Name: "domain", Value: "2", Domain: "example.com", MaxAge: 60},
+	})
+	jar.SetCookies(mustParseURL(t, "https://other.com/"), []*http.Cookie{
+		{Name: "other", Value: "3"},
+	})
+
+	assert.Equal(t, []string{"example.com", "other.com", "www.example.com"}, jar.Domains())
+
+	cookies := jar.DomainCookies("EXAMPLE.com")
+	if assert.Len(t, cookies, 2) {
+		assert.Equal(t, "domain", cookies[0].Name)
+		assert.Equal(t, "example.com", cookies[0].Domain)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), cookies[0].Expires, time.Second)
+
+		assert.Equal(t, "host", cookies[1].Name)
+		assert.Equal(t, "www.example.com", cookies[1].Domain)
+		assert.Equal(t, "/", cookies[1].Path)
+		assert.True(t, cookies[1].HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[1].SameSite)
+		assert.True(t, cookies[1].Expires.IsZero())
+	}
+	assert.Len(t, jar.DomainCookies("www.example.com"), 1)
+
+	jar.ClearDomain("www.example.com")
+	assert.Equal(t, []string{"example.com", "other.com"}, jar.Domains())
+
+	jar.ClearDomain("example.com")
+	assert.Equal(t, []string{"other.com"}, jar.Domains())
+
+	jar.Clear()
+	assert.Empty(t, jar.Domains())
+}
+
+func TestJar_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar := NewJar()
+	err := jar.Load(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	u := mustParseURL(t, "https://www.example.com/admin/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "1", HttpOnly: true},
+		{Name: "remember", Value: "2", Domain: "example.com", Path: "/", MaxAge: 3600, Secure: true},
+		{Name: "short", Value: "3", MaxAge: 1},
+	})
+	assert.NoError(t, jar.Save(path))
+
+	info, err := os.Stat(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	loaded := NewJar()
+	assert.NoError(t, loaded.Load(path))
+	assert.Equal(t, []string{"session=1", "remember=2"}, cookieNames(loaded.Cookies(u)))
+	assert.Equal(t, []string{"remember=2"}, cookieNames(loaded.Cookies(mustParseURL(t, "https://example.com/"))))
+
+	expected, actual := jar.DomainCookies("example.com"), loaded.DomainCookies("example.com")
+	if assert.Equal(t, cookieNames(expected), cookieNames(actual)) {
+		for i := range expected {
+			assert.True(t, expected[i].Expires.Equal(actual[i].Expires))
+			expected[i].Expires, actual[i].Expires = time.Time{}, time.Time{}
+			assert.Equal(t, expected[i], actual[i])
+		}
+	}
+
+	assert.Error(t, loaded.UnmarshalJSON([]byte(`{"cookies":`)))
+}
+
+func TestJar_UnmarshalJSON_PublicSuffix(t *testing.T) {
+	jar := NewJar()
+	assert.NoError(t, jar.UnmarshalJSON([]byte(`{"cookies":[
+		{"name":"sid","value":"1","domain":"co.uk","path":"/"},
+		{"name":"host","value":"2","domain":"co.uk","path":"/","host_only":true},
+		{"name":"site","value":"3","domain":"example.co.uk","path":"/"}
+	]}`)))
+
+	// The domain cookie of a public suffix is not sent to its subdomains.
+	assert.Equal(t, []string{"site=3"}, cookieNames(jar.Cookies(mustParseURL(t, "https://www.example.co.uk/"))))
+	assert.Equal(t, []string{"host=2"}, cookieNames(jar.Cookies(mustParseURL(t, "https://co.uk/"))))
+}
+
+func TestCookieJarSuite(t *testing.T) {
+	suite.Run(t, new(CookieJarSuite))
+}
+
+type CookieJarSuite struct {
+	echoSuite
+}
+
+func (suite *CookieJarSuite) Test_Session() {
+	jar := NewJar()
+	client := New(WithBaseURL(suite.server.URL), WithCookieJar(jar))
+
+	resp, err := client.Get("/session").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = client.Post("/login").Form(Params{{Key: "username", Value: "kitty"}}).Do()
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), resp.IsSuccess())
+
+	var session struct{ Username string }
+	resp, err = client.Get("/session").Do()
+	if assert.NoError(suite.T(), err) {
+		assert.NoError(suite.T(), resp.JSON(&session))
+		assert.Equal(suite.T(), "kitty", session.Username)
+	}
+
+	// A new client keeps the session through the saved jar.
+	path := filepath.Join(suite.T().TempDir(), "cookies.json")
+	assert.NoError(suite.T(), jar.Save(path))
+
+	loaded := NewJar()
+	assert.NoError(suite.T(), loaded.Load(path))
+	client = New(WithBaseURL(suite.server.URL), WithCookieJar(loaded))
+
+	resp, err = client.Get("/session").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Post("/logout").Do()
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), resp.IsSuccess())
+	assert.Empty(suite.T(), loaded.Domains())
+}
+
+func (suite *RequestSuite) Test_CookieJar_Retry() {
+	jar := NewJar()
+	u, _ := url.Parse(suite.server.URL)
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "kitty"}})
+
+	// The cookies of the jar are sent once by every attempt.
+	var retries int32
+	echo := suite.echo(New(WithCookieJar(jar)).Get(suite.flakyURL("fail=1")).Retry(testRetryPolicy(2, &retries)).Do())
+	assert.Equal(suite.T(), int32(1), retries)
+	assert.Equal(suite.T(), []string{"session=kitty"}, echo.Header["Cookie"])
+}
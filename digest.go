@@ -0,0 +1,388 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithDigestAuth set the default credentials of every request to the
+// digest access authentication of username and password, RFC 7616.
+// The challenge of a host is cached by the client, so the next requests
+// to the host are authenticated without being challenged again.
+func WithDigestAuth(username, password string) Option {
+	return func(ops *options) {
+		ops.credentials = digestCredentials(username, password)
+	}
+}
+
+// DigestAuth set the credentials of the request to the digest access
+// authentication of username and password, replacing the client ones,
+// see WithDigestAuth. The challenge is only cached for the request.
+func (r *Request) DigestAuth(username, password string) *Request {
+	return r.setCredentials(digestCredentials(username, password))
+}
+
+func digestCredentials(username, password string) *credentials {
+	return &credentials{
+		header: "Authorization",
+		auth: &digestAuth{
+			username:   username,
+			password:   password,
+			challenges: make(map[string]*digestChallenge),
+		},
+	}
+}
+
+// digestAuth answers the digest challenges of the hosts.
+type digestAuth struct {
+	username   string
+	password   string
+	challenges map[string]*digestChallenge // By host.
+	mux        sync.Mutex
+}
+
+// digestChallenge is a challenge of the WWW-Authenticate header.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string // The chosen one, empty for RFC 2069.
+	userhash  bool
+	nc        uint32 // The count of the requests sent with the nonce.
+}
+
+// do execute req by send, authenticated by the challenge cached for its
+// host. If the response is a digest challenge, req is sent again once,
+// answering it.
+//
+// A challenge is only answered for the origin of req, so a redirect to
+// another origin never gets the credentials. The body of req must be
+// rewindable to be sent again.
+func (auth *digestAuth) do(send Handler, req *http.Request) (*http.Response, error) {
+	if challenge, ok := auth.next(req.URL.Host); ok {
+		if authorized, err := auth.authorize(req, challenge); err == nil {
+			req = authorized
+		}
+	}
+
+	resp, err := send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !rewindable(req) {
+		return resp, err
+	}
+
+	parsed := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if parsed == nil {
+		return resp, nil
+	}
+
+	// Answer the last request, which differs from req after a redirect.
+	last := resp.Request
+	if last == nil {
+		last = req
+	}
+	if !sameOrigin(last.URL, req.URL) || last.Method != req.Method {
+		return resp, nil
+	}
+
+	next, err := rewind(req)
+	if err != nil {
+		return resp, nil
+	}
+	next.URL, next.Host = last.URL, ""
+
+	auth.mux.Lock()
+	auth.challenges[next.URL.Host] = parsed
+	auth.mux.Unlock()
+
+	challenge, _ := auth.next(next.URL.Host)
+	authorized, err := auth.authorize(next, challenge)
+	if err != nil {
+		return resp, nil
+	}
+
+	drainBody(resp)
+
+	return send(authorized)
+}
+
+// next returns a copy of the challenge cached for host,
+// incrementing its nonce count.
+func (auth *digestAuth) next(host string) (digestChallenge, bool) {
+	auth.mux.Lock()
+	defer auth.mux.Unlock()
+
+	challenge, ok := auth.challenges[host]
+	if !ok {
+		return digestChallenge{}, false
+	}
+	challenge.nc++
+	return *challenge, true
+}
+
+// authorize returns a copy of req with the Authorization header
+// answering challenge.
+func (auth *digestAuth) authorize(req *http.Request, challenge digestChallenge) (*http.Request, error) {
+	cnonce, err := digestCnonce()
+	if err != nil {
+		return nil, err
+	}
+
+	var body string
+	if challenge.qop == "auth-int" {
+		if body, err = digestBody(req, digestHash(challenge.algorithm)); err != nil {
+			return nil, err
+		}
+	}
+
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", auth.authorization(&challenge, req.Method, req.URL.RequestURI(), cnonce, body))
+	return authorized, nil
+}
+
+// authorization returns the credentials answering challenge, RFC 7616
+// section 3.4. body is the hash of the body for the auth-int quality
+// of protection.
+func (auth *digestAuth) authorization(challenge *digestChallenge, method, uri, cnonce, body string) string {
+	newHash := digestHash(challenge.algorithm)
+	hexHash := func(s string) string {
+		h := newHash()
+		io.WriteString(h, s)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	nc := fmt.Sprintf("%08x", challenge.nc)
+
+	a1 := auth.username + ":" + challenge.realm + ":" + auth.password
+	if strings.HasSuffix(strings.ToUpper(challenge.algorithm), "-SESS") {
+		a1 = hexHash(a1) + ":" + challenge.nonce + ":" + cnonce
+	}
+
+	a2 := method + ":" + uri
+	if challenge.qop == "auth-int" {
+		a2 += ":" + body
+	}
+
+	var response string
+	if challenge.qop == "" {
+		response = hexHash(hexHash(a1) + ":" + challenge.nonce + ":" + hexHash(a2))
+	} else {
+		response = hexHash(strings.Join([]string{hexHash(a1), challenge.nonce, nc, cnonce, challenge.qop, hexHash(a2)}, ":"))
+	}
+
+	username := auth.username
+	if challenge.userhash {
+		username = hexHash(auth.username + ":" + challenge.realm)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Digest username=%s, realm=%s, uri=%s", digestQuote(username), digestQuote(challenge.realm), digestQuote(uri))
+	if challenge.algorithm != "" {
+		fmt.Fprintf(&b, ", algorithm=%s", challenge.algorithm)
+	}
+	fmt.Fprintf(&b, ", nonce=%s", digestQuote(challenge.nonce))
+	if challenge.qop != "" {
+		fmt.Fprintf(&b, ", nc=%s, cnonce=%s, qop=%s", nc, digestQuote(cnonce), challenge.qop)
+	}
+	fmt.Fprintf(&b, ", response=%s", digestQuote(response))
+	if challenge.opaque != "" {
+		fmt.Fprintf(&b, ", opaque=%s", digestQuote(challenge.opaque))
+	}
+	if challenge.userhash {
+		b.WriteString(", userhash=true")
+	}
+	return b.String()
+}
+
+// digestBody returns the hash of the body of req, which is read from
+// GetBody, for the auth-int quality of protection.
+func digestBody(req *http.Request, newHash func() hash.Hash) (string, error) {
+	h := newHash()
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", fmt.Errorf("httpclient: digest auth-int requires a rewindable body")
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digestAlgorithms ranks the supported algorithms, the strongest first.
+var digestAlgorithms = []string{"SHA-512-256", "SHA-256", "MD5"}
+
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "SHA-512-256":
+		return sha512.New512_256
+	case "SHA-256":
+		return sha256.New
+	case "", "MD5":
+		return md5.New
+	}
+	return nil
+}
+
+// parseDigestChallenge returns the strongest supported digest challenge
+// of the WWW-Authenticate header values, or nil if there is none.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var chosen *digestChallenge
+	rank := len(digestAlgorithms)
+
+	var challenges []map[string]string
+	for _, value := range values {
+		challenges = append(challenges, parseDigestChallenges(value)...)
+	}
+
+	for _, params := range challenges {
+		if params["nonce"] == "" || digestHash(params["algorithm"]) == nil {
+			continue
+		}
+
+		challenge := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+
+		// Prefer auth, which does not read the body again.
+		if qop, ok := params["qop"]; ok {
+			offered := make(map[string]bool)
+			for _, item := range strings.Split(qop, ",") {
+				offered[strings.ToLower(strings.TrimSpace(item))] = true
+			}
+			switch {
+			case offered["auth"]:
+				challenge.qop = "auth"
+			case offered["auth-int"]:
+				challenge.qop = "auth-int"
+			default:
+				continue
+			}
+		}
+
+		algorithm := strings.TrimSuffix(strings.ToUpper(challenge.algorithm), "-SESS")
+		if algorithm == "" {
+			algorithm = "MD5"
+		}
+		for i, supported := range digestAlgorithms {
+			if algorithm == supported && i < rank {
+				chosen, rank = challenge, i
+			}
+		}
+	}
+	return chosen
+}
+
+// parseDigestChallenges parses the auth-params of the Digest challenges
+// of a WWW-Authenticate header value, which may list challenges of other
+// schemes, keyed by their lowercase names.
+func parseDigestChallenges(value string) []map[string]string {
+	var challenges []map[string]string
+	s := value
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if s == "" {
+			return challenges
+		}
+
+		end := strings.IndexAny(s, " ,")
+		if end < 0 {
+			end = len(s)
+		}
+		scheme := s[:end]
+
+		params, rest, ok := parseAuthParams(s[end:])
+		if !ok {
+			return challenges
+		}
+		if strings.EqualFold(scheme, "digest") {
+			challenges = append(challenges, params)
+		}
+		s = rest
+	}
+}
+
+// parseAuthParams parses the auth-params of a challenge, up to the next
+// challenge, returned as the rest of s. It fails on an unterminated
+// quoted string.
+func parseAuthParams(s string) (params map[string]string, rest string, ok bool) {
+	params = make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 || strings.ContainsAny(s[:eq], " ,") {
+			return params, s, true
+		}
+		key := strings.ToLower(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " ")
+
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, "", false
+			}
+			params[key] = b.String()
+			s = s[i+1:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			params[key] = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+	}
+}
+
+func digestQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func digestCnonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/coolstina/httpclient/test/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestAuth_Authorization(t *testing.T) {
+	// The examples of RFC 7616 section 3.9.1.
+	auth := &digestAuth{username: "Mufasa", password: "Circle of Life"}
+
+	grids := []struct {
+		algorithm string
+		response  string
+	}{
+		{algorithm: "MD5", response: "8ca523f5e9506fed4657c9700eebdbec"},
+		{algorithm: "SHA-256", response: "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	for _, grid := range grids {
+		challenge := &digestChallenge{
+			realm:     "http-auth@example.org",
+			nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+			opaque:    "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+			algorithm: grid.algorithm,
+			qop:       "auth",
+			nc:        1,
+		}
+
+		actual := auth.authorization(challenge, http.MethodGet, "/dir/index.html", "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", "")
+		assert.Equal(t, `Digest username="Mufasa", realm="http-auth@example.org", uri="/dir/index.html", `+
+			`algorithm=`+grid.algorithm+`, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", `+
+			`nc=00000001, cnonce="f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", qop=auth, `+
+			`response="`+grid.response+`", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`, actual)
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	grids := []struct {
+		values   []string
+		expected *digestChallenge
+	}{
+		{
+			values: []string{`Basic realm="test"`},
+		},
+		{
+			values:   []string{`Digest realm="test", nonce="abc", qop="auth,auth-int", opaque="xyz"`},
+			expected: &digestChallenge{realm: "test", nonce: "abc", opaque: "xyz", qop: "auth"},
+		},
+		{
+			values: []string{
+				`Digest realm="test", nonce="md5", algorithm=MD5, qop="auth"`,
+				`Digest realm="test", nonce="sha", algorithm=SHA-256-sess, qop="auth-int", userhash=TRUE`,
+			},
+			expected: &digestChallenge{realm: "test", nonce: "sha", algorithm: "SHA-256-sess", qop: "auth-int", userhash: true},
+		},
+		{
+			values:   []string{`digest realm="a \"quoted\" realm", nonce="abc"`},
+			expected: &digestChallenge{realm: `a "quoted" realm`, nonce: "abc"},
+		},
+		{
+			values:   []string{`Digest realm="test", nonce="abc", algorithm=MD5, Basic realm="test"`},
+			expected: &digestChallenge{realm: "test", nonce: "abc", algorithm: "MD5"},
+		},
+		{
+			values:   []string{`Basic realm="test", Digest realm="test", nonce="abc", qop="auth"`},
+			expected: &digestChallenge{realm: "test", nonce: "abc", qop: "auth"},
+		},
+		{
+			values:   []string{`Negotiate abc==, Bearer, Digest realm="test", nonce="abc"`},
+			expected: &digestChallenge{realm: "test", nonce: "abc"},
+		},
+		{
+			values: []string{
+				`Digest realm="test", nonce="md5", algorithm=MD5, Basic realm="test", ` +
+					`Digest realm="test", nonce="sha", algorithm=SHA-256`,
+			},
+			expected: &digestChallenge{realm: "test", nonce: "sha", algorithm: "SHA-256"},
+		},
+		{
+			values: []string{`Digest realm="test", nonce="abc", algorithm=SHA-1`},
+		},
+		{
+			values: []string{`Digest realm="test", nonce="abc", qop="token"`},
+		},
+		{
+			values: []string{`Digest realm="test", nonce="abc`},
+		},
+		{
+			values: []string{`Digest realm="test"`},
+		},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, parseDigestChallenge(grid.values), "%v", grid.values)
+	}
+}
+
+func (suite *RequestSuite) Test_DigestAuth() {
+	grids := []struct {
+		query string
+	}{
+		{query: ""},
+		{query: "algorithm=MD5-sess"},
+		{query: "algorithm=SHA-256"},
+		{query: "algorithm=SHA-256-sess&userhash"},
+		{query: "algorithm=SHA-256&qop=auth-int"},
+		{query: "qop=auth-int,auth"},
+	}
+
+	for _, grid := range grids {
+		client := New(WithBaseURL(suite.server.URL), WithDigestAuth(server.DigestUsername, server.DigestPassword))
+
+		// The challenge of the first request is cached,
+		// so the second one is not challenged again.
+		for _, nc := range []string{"00000001", "00000002"} {
+			resp, err := client.Post("/digest?" + grid.query).JSON(map[string]int{"id": 1}).Do()
+			echo := suite.echo(resp, err)
+			assert.Equal(suite.T(), nc, resp.Header.Get("X-Digest-Nc"), grid.query)
+			assert.Equal(suite.T(), `{"id":1}`, echo.Body, grid.query)
+		}
+	}
+}
+
+func (suite *RequestSuite) Test_DigestAuth_Unauthorized() {
+	resp, err := New().Get(suite.server.URL+"/digest").DigestAuth(server.DigestUsername, "wrong").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+
+	// A body which cannot be sent again is not replayed.
+	resp, err = New().Post(suite.server.URL+"/digest").
+		Body(ioutil.NopCloser(bytes.NewReader([]byte("body")))).
+		DigestAuth(server.DigestUsername, server.DigestPassword).
+		Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (suite *RequestSuite) Test_DigestAuth_Redirect() {
+	// The challenge of the redirected request is answered.
+	req := New().Get(suite.server.URL+"/redirect?to=/digest").DigestAuth(server.DigestUsername, server.DigestPassword)
+	echo := suite.echo(req.Do())
+	assert.Equal(suite.T(), "/digest", echo.Path)
+}
@@ -14,196 +14,39 @@
 
 package httpclient
 
-import (
-	"context"
-	"crypto/tls"
-	"io"
-	"log"
-	"net/http"
-	"strings"
-	"sync"
-	"time"
-)
-
-var client *HttpClient
-var once sync.Once
-
-type HttpClient struct {
-	client      *http.Client
-	transport   *http.Transport
-	method      string
-	timeout     time.Duration
-	url         string
-	body        io.Reader // Use POST/PUT/DELETE
-	queryParams Params
-	debug       bool
-	mux         sync.Mutex
-
-	ctx    context.Context
-	cancel context.CancelFunc
-}
-
-func NewHttpClientOr() *HttpClient {
-	once.Do(func() {
-		client = &HttpClient{
-			client: &http.Client{},
-		}
-	})
-	return client
-}
-
-func (cli *HttpClient) init() *HttpClient {
-	cli.method = ""
-	cli.url = ""
-	cli.body = nil
-	cli.queryParams = nil
-	cli.debug = false
-	cli.transport = nil
-	return cli
-}
-
-func (cli *HttpClient) newRequest(method string, url string) *HttpClient {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	// Reset parameters.
-	cli.init()
-
-	// New parameters.
-	cli.method = method
-	cli.url = url
-	return cli
-}
-
-func (cli *HttpClient) Body(body io.Reader) *HttpClient {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	cli.body = body
-	return cli
-}
-
-func (cli *HttpClient) BodyWithJSON(s string) *HttpClient {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	cli.body = strings.NewReader(s)
-	return cli
-}
-
-func (cli *HttpClient) QueryParams(params Params) *HttpClient {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	cli.queryParams = params
-	return cli
-}
-
-func (cli *HttpClient) Timeout(wait time.Duration) *HttpClient {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	cli.timeout = wait
-	return cli
-}
-
-func (cli *HttpClient) InsecureSkipVerify(skip bool) *HttpClient {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	if skip {
-		if cli.transport == nil {
-			cli.transport = &http.Transport{}
-		}
+// HttpClient is the former name of Request.
+//
+// Deprecated: use Request.
+type HttpClient = Request
 
-		if cli.transport.TLSClientConfig == nil {
-			cli.transport.TLSClientConfig = &tls.Config{}
-		}
-		cli.transport.TLSClientConfig.InsecureSkipVerify = true
-	}
-	return cli
+func Get(url string) *Request {
+	return NewHttpClientOr().Get(url)
 }
 
-func (cli *HttpClient) Debug(debug bool) *HttpClient {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	cli.debug = debug
-	return cli
+func Post(url string) *Request {
+	return NewHttpClientOr().Post(url)
 }
 
-func (cli *HttpClient) Do() (*http.Response, error) {
-	cli.mux.Lock()
-	defer cli.mux.Unlock()
-
-	// Use transport.
-	cli.useTransport()
-
-	// New request.
-	req, err := http.NewRequest(cli.method, cli.url, cli.body)
-	if err != nil {
-		return nil, err
-	}
-
-	// Use query parameters,
-	// if request Method is GET and call QueryParams method,
-	// then use
-	cli.useQueryParams(req)
-
-	// Show debug information.
-	cli.showDebug(req)
-
-	// Use timeout.
-	req = cli.useTimeout(req)
-	if cli.cancel != nil {
-		defer cli.cancel()
-	}
-
-	// Execute http request.
-	resp, err := cli.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp, nil
-}
-
-func (cli *HttpClient) useQueryParams(req *http.Request) {
-	if cli.queryParams != nil {
-		query := req.URL.Query()
-
-		for _, param := range cli.queryParams {
-			query.Add(param.Key, param.Value)
-		}
-
-		req.URL.RawQuery = query.Encode()
-	}
+func Put(url string) *Request {
+	return NewHttpClientOr().Put(url)
 }
 
-func (cli *HttpClient) useTransport() {
-	if cli.transport != nil {
-		cli.client.Transport = cli.transport
-	}
+func Patch(url string) *Request {
+	return NewHttpClientOr().Patch(url)
 }
 
-func (cli *HttpClient) showDebug(req *http.Request) {
-	if cli.debug {
-		log.Printf("fill url: %s\n", req.URL.String())
-	}
+func Delete(url string) *Request {
+	return NewHttpClientOr().Delete(url)
 }
 
-func (cli *HttpClient) useTimeout(req *http.Request) *http.Request {
-	if cli.timeout != 0 {
-		cli.ctx, cli.cancel = context.WithTimeout(context.Background(), cli.timeout)
-		return req.WithContext(cli.ctx)
-	}
-	return req
+func Head(url string) *Request {
+	return NewHttpClientOr().Head(url)
 }
 
-func Get(url string) *HttpClient {
-	return NewHttpClientOr().newRequest(http.MethodGet, url)
+func Options(url string) *Request {
+	return NewHttpClientOr().Options(url)
 }
 
-func Post(url string) *HttpClient {
-	return NewHttpClientOr().newRequest(http.MethodPost, url)
+func Method(verb string, url string) *Request {
+	return NewHttpClientOr().Method(verb, url)
 }
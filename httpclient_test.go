@@ -17,13 +17,17 @@ package httpclient
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/coolstina/httpclient/test/server"
+	"github.com/gin-gonic/gin"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -140,3 +144,31 @@ func Test_AddUsers_With_Timeout_False(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, actual)
 }
+
+func BenchmarkGet_Parallel(b *testing.B) {
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = ioutil.Discard
+	defer func() {
+		gin.SetMode(gin.DebugMode)
+		gin.DefaultWriter = os.Stdout
+	}()
+
+	srv := httptest.NewServer(server.NewEngine())
+	defer srv.Close()
+
+	cli := New(WithBaseURL(srv.URL))
+	cli.client.Transport.(*http.Transport).MaxIdleConnsPerHost = 64
+
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			resp, err := cli.Get("/users").Do()
+			if err != nil {
+				b.Fatal(err)
+			}
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+)
+
+// Algorithm signs the signature bases, and verifies their signatures.
+type Algorithm interface {
+	// Name returns the name of the algorithm in the HTTP Signature
+	// Algorithms registry, e.g. hmac-sha256.
+	Name() string
+	// Sign returns the signature of base.
+	Sign(base []byte) ([]byte, error)
+	// Verify returns an error if signature is not the one of base.
+	Verify(base, signature []byte) error
+}
+
+// ErrInvalidSignature is returned if a signature is not the one of
+// the signed message.
+var ErrInvalidSignature = errors.New("httpsig: invalid signature")
+
+var errNoPrivateKey = errors.New("httpsig: no private key")
+
+// HMACSHA256 returns the hmac-sha256 algorithm with the shared key.
+func HMACSHA256(key []byte) Algorithm {
+	return hmacSHA256{key: key}
+}
+
+type hmacSHA256 struct {
+	key []byte
+}
+
+func (alg hmacSHA256) Name() string {
+	return "hmac-sha256"
+}
+
+func (alg hmacSHA256) Sign(base []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, alg.key)
+	mac.Write(base)
+	return mac.Sum(nil), nil
+}
+
+func (alg hmacSHA256) Verify(base, signature []byte) error {
+	expected, _ := alg.Sign(base)
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// RSASHA256 returns the rsa-v1_5-sha256 algorithm with the private key,
+// the RSASSA-PKCS1-v1_5 signatures of the SHA-256 digests.
+func RSASHA256(key *rsa.PrivateKey) Algorithm {
+	return rsaSHA256{private: key, public: &key.PublicKey}
+}
+
+// RSASHA256Public returns the rsa-v1_5-sha256 algorithm with the public
+// key, which only verifies signatures.
+func RSASHA256Public(key *rsa.PublicKey) Algorithm {
+	return rsaSHA256{public: key}
+}
+
+type rsaSHA256 struct {
+	private *rsa.PrivateKey
+	public  *rsa.PublicKey
+}
+
+func (alg rsaSHA256) Name() string {
+	return "rsa-v1_5-sha256"
+}
+
+func (alg rsaSHA256) Sign(base []byte) ([]byte, error) {
+	if alg.private == nil {
+		return nil, errNoPrivateKey
+	}
+	digest := sha256.Sum256(base)
+	return rsa.SignPKCS1v15(rand.Reader, alg.private, crypto.SHA256, digest[:])
+}
+
+func (alg rsaSHA256) Verify(base, signature []byte) error {
+	digest := sha256.Sum256(base)
+	if rsa.VerifyPKCS1v15(alg.public, crypto.SHA256, digest[:], signature) != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Ed25519 returns the ed25519 algorithm with the private key.
+func Ed25519(key ed25519.PrivateKey) Algorithm {
+	return ed25519Algorithm{private: key, public: key.Public().(ed25519.PublicKey)}
+}
+
+// Ed25519Public returns the ed25519 algorithm with the public key,
+// which only verifies signatures.
+func Ed25519Public(key ed25519.PublicKey) Algorithm {
+	return ed25519Algorithm{public: key}
+}
+
+type ed25519Algorithm struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func (alg ed25519Algorithm) Name() string {
+	return "ed25519"
+}
+
+func (alg ed25519Algorithm) Sign(base []byte) ([]byte, error) {
+	if alg.private == nil {
+		return nil, errNoPrivateKey
+	}
+	return ed25519.Sign(alg.private, base), nil
+}
+
+func (alg ed25519Algorithm) Verify(base, signature []byte) error {
+	if !ed25519.Verify(alg.public, base, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpsig
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	grids := []struct {
+		signer   Algorithm
+		verifier Algorithm
+		name     string
+	}{
+		{signer: HMACSHA256([]byte("secret")), verifier: HMACSHA256([]byte("secret")), name: "hmac-sha256"},
+		{signer: RSASHA256(rsaKey), verifier: RSASHA256Public(&rsaKey.PublicKey), name: "rsa-v1_5-sha256"},
+		{signer: Ed25519(ed25519Key), verifier: Ed25519Public(ed25519Key.Public().(ed25519.PublicKey)), name: "ed25519"},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.name, grid.signer.Name())
+		assert.Equal(t, grid.name, grid.verifier.Name())
+
+		signature, err := grid.signer.Sign([]byte("base"))
+		assert.NoError(t, err)
+		assert.NoError(t, grid.verifier.Verify([]byte("base"), signature), grid.name)
+		assert.Equal(t, ErrInvalidSignature, grid.verifier.Verify([]byte("other"), signature), grid.name)
+	}
+}
+
+func TestAlgorithm_PublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for _, alg := range []Algorithm{RSASHA256Public(&rsaKey.PublicKey), Ed25519Public(ed25519Key)} {
+		_, err := alg.Sign([]byte("base"))
+		assert.Equal(t, errNoPrivateKey, err)
+	}
+}
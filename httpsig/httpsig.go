@@ -0,0 +1,420 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpsig signs and verifies HTTP requests by the HTTP message
+// signatures of RFC 9421.
+package httpsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoSignature is returned by Verify if the request is not signed.
+var ErrNoSignature = errors.New("httpsig: no signature")
+
+// Signer signs requests, adding the Signature-Input and Signature headers.
+//
+// The covered components are derived components, e.g. @method,
+// @target-uri, @authority, @scheme, @request-target, @path and @query,
+// or header fields, e.g. content-type. The content-digest header is set
+// to the SHA-256 digest of the body if it is covered and not set, RFC 9530.
+type Signer struct {
+	Label      string // sig1 if empty.
+	KeyID      string
+	Algorithm  Algorithm
+	Components []string
+	Expires    time.Duration // The signature does not expire if zero.
+	Nonce      string
+	Tag        string
+	WithAlg    bool // Add the alg parameter, which verifiers may ignore.
+}
+
+// Sign signs req at now, the body is read by GetBody if its digest
+// is covered.
+func (signer *Signer) Sign(req *http.Request, now time.Time) error {
+	if covers(signer.Components, "content-digest") && req.Header.Get("Content-Digest") == "" {
+		digest, err := contentDigest(req)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Digest", digest)
+	}
+
+	params := signer.params(now)
+	base, err := signatureBase(req, signer.Components, params)
+	if err != nil {
+		return err
+	}
+	signature, err := signer.Algorithm.Sign(base)
+	if err != nil {
+		return err
+	}
+
+	label := signer.Label
+	if label == "" {
+		label = "sig1"
+	}
+	req.Header.Set("Signature-Input", label+"="+params)
+	req.Header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+	return nil
+}
+
+// params returns the serialized signature parameters.
+func (signer *Signer) params(now time.Time) string {
+	var buf strings.Builder
+	buf.WriteByte('(')
+	for i, component := range signer.Components {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(strconv.Quote(strings.ToLower(component)))
+	}
+	buf.WriteByte(')')
+
+	fmt.Fprintf(&buf, ";created=%d", now.Unix())
+	if signer.Expires > 0 {
+		fmt.Fprintf(&buf, ";expires=%d", now.Add(signer.Expires).Unix())
+	}
+	if signer.Nonce != "" {
+		fmt.Fprintf(&buf, ";nonce=%q", signer.Nonce)
+	}
+	if signer.WithAlg {
+		fmt.Fprintf(&buf, ";alg=%q", signer.Algorithm.Name())
+	}
+	if signer.KeyID != "" {
+		fmt.Fprintf(&buf, ";keyid=%q", signer.KeyID)
+	}
+	if signer.Tag != "" {
+		fmt.Fprintf(&buf, ";tag=%q", signer.Tag)
+	}
+	return buf.String()
+}
+
+// Verifier verifies the signatures of requests.
+type Verifier struct {
+	// Keys returns the algorithm of the key, or an error
+	// if the key is unknown.
+	Keys func(keyID string) (Algorithm, error)
+	// Required are the components which every signature must cover.
+	Required []string
+	// MaxAge is the maximum age of a signature, unlimited if zero.
+	MaxAge time.Duration
+}
+
+// Verify verifies every signature of req at now, the covered digest
+// of the body is verified too.
+func (verifier *Verifier) Verify(req *http.Request, now time.Time) error {
+	inputs := parseDictionary(req.Header.Values("Signature-Input"))
+	signatures := parseDictionary(req.Header.Values("Signature"))
+	if len(inputs) == 0 {
+		return ErrNoSignature
+	}
+
+	for label, input := range inputs {
+		components, params, err := parseSignatureInput(input)
+		if err != nil {
+			return err
+		}
+		for _, required := range verifier.Required {
+			if !covers(components, required) {
+				return fmt.Errorf("httpsig: %s does not cover %s", label, required)
+			}
+		}
+
+		created, _ := strconv.ParseInt(params["created"], 10, 64)
+		if verifier.MaxAge > 0 && now.Sub(time.Unix(created, 0)) > verifier.MaxAge {
+			return fmt.Errorf("httpsig: %s is too old", label)
+		}
+		if expires, ok := params["expires"]; ok {
+			if unix, err := strconv.ParseInt(expires, 10, 64); err != nil || now.Unix() > unix {
+				return fmt.Errorf("httpsig: %s expired", label)
+			}
+		}
+
+		alg, err := verifier.Keys(params["keyid"])
+		if err != nil {
+			return err
+		}
+		if name, ok := params["alg"]; ok && name != alg.Name() {
+			return fmt.Errorf("httpsig: %s is not signed by %s", label, alg.Name())
+		}
+
+		encoded := signatures[label]
+		if len(encoded) < 2 || encoded[0] != ':' || encoded[len(encoded)-1] != ':' {
+			return fmt.Errorf("httpsig: no signature %s", label)
+		}
+		signature, err := base64.StdEncoding.DecodeString(encoded[1 : len(encoded)-1])
+		if err != nil {
+			return fmt.Errorf("httpsig: %s: %w", label, err)
+		}
+
+		base, err := signatureBase(req, components, input)
+		if err != nil {
+			return err
+		}
+		if err := alg.Verify(base, signature); err != nil {
+			return err
+		}
+
+		if covers(components, "content-digest") {
+			if err := verifyContentDigest(req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// signatureBase returns the signature base of the components of req,
+// RFC 9421 section 2.5.
+func signatureBase(req *http.Request, components []string, params string) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, component := range components {
+		component = strings.ToLower(component)
+		value, err := componentValue(req, component)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "%q: %s\n", component, value)
+	}
+	fmt.Fprintf(&buf, "%q: %s", "@signature-params", params)
+	return buf.Bytes(), nil
+}
+
+func componentValue(req *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return req.Method, nil
+	case "@target-uri":
+		return scheme(req) + "://" + authority(req) + requestTarget(req), nil
+	case "@authority":
+		return authority(req), nil
+	case "@scheme":
+		return scheme(req), nil
+	case "@request-target":
+		return requestTarget(req), nil
+	case "@path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	}
+
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("httpsig: unsupported component %s", component)
+	}
+	values := req.Header.Values(component)
+	if len(values) == 0 {
+		return "", fmt.Errorf("httpsig: no header %s", component)
+	}
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// scheme returns the scheme of req, which a server request only has
+// by its TLS state.
+func scheme(req *http.Request) string {
+	switch {
+	case req.URL.Scheme != "":
+		return strings.ToLower(req.URL.Scheme)
+	case req.TLS != nil:
+		return "https"
+	}
+	return "http"
+}
+
+// authority returns the host of req, without the default port.
+func authority(req *http.Request) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host = strings.ToLower(host)
+
+	switch scheme(req) {
+	case "http":
+		return strings.TrimSuffix(host, ":80")
+	case "https":
+		return strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
+
+func requestTarget(req *http.Request) string {
+	target := req.URL.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	return target
+}
+
+func covers(components []string, component string) bool {
+	for _, covered := range components {
+		if strings.EqualFold(covered, component) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentDigest returns the Content-Digest header of the body of req.
+func contentDigest(req *http.Request) (string, error) {
+	var body io.Reader = http.NoBody
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case req.GetBody == nil:
+		return "", errors.New("httpsig: the body of the request is not rewindable")
+	default:
+		rc, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		body = rc
+	}
+
+	digest := sha256.New()
+	if _, err := io.Copy(digest, body); err != nil {
+		return "", err
+	}
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(digest.Sum(nil)) + ":", nil
+}
+
+// verifyContentDigest verifies the Content-Digest header of the body
+// of the received req, which is read and replaced.
+func verifyContentDigest(req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		read, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(read))
+		body = read
+	}
+
+	digests := parseDictionary(req.Header.Values("Content-Digest"))
+	var verified bool
+	for algorithm, encoded := range digests {
+		var sum []byte
+		switch algorithm {
+		case "sha-256":
+			digest := sha256.Sum256(body)
+			sum = digest[:]
+		case "sha-512":
+			digest := sha512.Sum512(body)
+			sum = digest[:]
+		default:
+			continue
+		}
+		if encoded != ":"+base64.StdEncoding.EncodeToString(sum)+":" {
+			return errors.New("httpsig: invalid content digest")
+		}
+		verified = true
+	}
+	if !verified {
+		return errors.New("httpsig: no supported content digest")
+	}
+	return nil
+}
+
+// parseDictionary parses the members of a structured field dictionary,
+// RFC 8941, keeping their values serialized.
+func parseDictionary(values []string) map[string]string {
+	members := make(map[string]string)
+	for _, value := range values {
+		for _, member := range splitOutsideQuotes(value, ',') {
+			key, value := member, ""
+			if i := strings.IndexByte(member, '='); i >= 0 {
+				key, value = member[:i], member[i+1:]
+			}
+			if key = strings.TrimSpace(key); key != "" {
+				members[key] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return members
+}
+
+// parseSignatureInput parses a member of the Signature-Input header,
+// e.g. ("@method" "@authority");created=1618884473;keyid="key".
+func parseSignatureInput(input string) (components []string, params map[string]string, err error) {
+	end := strings.IndexByte(input, ')')
+	if !strings.HasPrefix(input, "(") || end < 0 {
+		return nil, nil, fmt.Errorf("httpsig: invalid signature input %s", input)
+	}
+
+	for _, item := range strings.Fields(input[1:end]) {
+		component, err := strconv.Unquote(item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("httpsig: invalid component %s", item)
+		}
+		components = append(components, component)
+	}
+
+	params = make(map[string]string)
+	for _, param := range splitOutsideQuotes(input[end+1:], ';') {
+		i := strings.IndexByte(param, '=')
+		if i < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(param[:i]), strings.TrimSpace(param[i+1:])
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		params[key] = value
+	}
+	return components, params, nil
+}
+
+// splitOutsideQuotes splits s by sep, except inside quoted strings.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	var quoted, escaped bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
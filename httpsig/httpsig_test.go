@@ -0,0 +1,196 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpsig
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The test keys of RFC 9421 appendix B.1.
+const (
+	testSharedSecret = "uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ=="
+	testKeyEd25519   = "MC4CAQAwBQYDK2VwBCIEIJ+DYvh6SEqVTm50DFtMDoQikTmiCqirVv9mWG9qfSnF"
+)
+
+// testRequest returns the test request of RFC 9421 section 2.4.
+func testRequest() *http.Request {
+	body := `{"hello": "world"}`
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/foo?param=Value&Pet=dog", strings.NewReader(body))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Digest", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:")
+	req.Header.Set("Content-Length", "18")
+	return req
+}
+
+func testEd25519Key(t *testing.T) ed25519.PrivateKey {
+	der, err := base64.StdEncoding.DecodeString(testKeyEd25519)
+	assert.NoError(t, err)
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	assert.NoError(t, err)
+	return key.(ed25519.PrivateKey)
+}
+
+func TestSigner_Sign(t *testing.T) {
+	secret, _ := base64.StdEncoding.DecodeString(testSharedSecret)
+	created := time.Unix(1618884473, 0)
+
+	// The examples of RFC 9421 appendix B.2.
+	grids := []struct {
+		signer    *Signer
+		input     string
+		signature string
+	}{
+		{
+			signer: &Signer{
+				Label:      "sig-b25",
+				KeyID:      "test-shared-secret",
+				Algorithm:  HMACSHA256(secret),
+				Components: []string{"date", "@authority", "content-type"},
+			},
+			input:     `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`,
+			signature: `sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:`,
+		},
+		{
+			signer: &Signer{
+				Label:      "sig-b26",
+				KeyID:      "test-key-ed25519",
+				Algorithm:  Ed25519(testEd25519Key(t)),
+				Components: []string{"date", "@method", "@path", "@authority", "content-type", "content-length"},
+			},
+			input:     `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`,
+			signature: `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:`,
+		},
+	}
+
+	for _, grid := range grids {
+		req := testRequest()
+		assert.NoError(t, grid.signer.Sign(req, created))
+		assert.Equal(t, grid.input, req.Header.Get("Signature-Input"))
+		assert.Equal(t, grid.signature, req.Header.Get("Signature"))
+	}
+}
+
+func TestSignatureBase(t *testing.T) {
+	req := testRequest()
+	base, err := signatureBase(req, []string{"@method", "@target-uri", "@authority", "@scheme", "@request-target", "@path", "@query"}, "()")
+	assert.NoError(t, err)
+	assert.Equal(t, `"@method": POST
+"@target-uri": https://example.com/foo?param=Value&Pet=dog
+"@authority": example.com
+"@scheme": https
+"@request-target": /foo?param=Value&Pet=dog
+"@path": /foo
+"@query": ?param=Value&Pet=dog
+"@signature-params": ()`, string(base))
+
+	_, err = signatureBase(req, []string{"x-missing"}, "()")
+	assert.EqualError(t, err, "httpsig: no header x-missing")
+	_, err = signatureBase(req, []string{"@status"}, "()")
+	assert.EqualError(t, err, "httpsig: unsupported component @status")
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key := testEd25519Key(t)
+	now := time.Now()
+	signer := &Signer{
+		KeyID:      "test-key-ed25519",
+		Algorithm:  Ed25519(key),
+		Components: []string{"@method", "@target-uri", "content-digest"},
+		Expires:    time.Minute,
+		WithAlg:    true,
+	}
+	verifier := &Verifier{
+		Keys: func(keyID string) (Algorithm, error) {
+			if keyID != "test-key-ed25519" {
+				return nil, ErrInvalidSignature
+			}
+			return Ed25519Public(key.Public().(ed25519.PublicKey)), nil
+		},
+		Required: []string{"@method"},
+		MaxAge:   time.Minute,
+	}
+
+	grids := []struct {
+		name     string
+		modify   func(req *http.Request)
+		at       time.Time
+		expected string
+	}{
+		{name: "valid", modify: func(req *http.Request) {}, at: now},
+		{
+			name:     "unsigned",
+			modify:   func(req *http.Request) { req.Header.Del("Signature-Input") },
+			at:       now,
+			expected: "httpsig: no signature",
+		},
+		{
+			name:     "method",
+			modify:   func(req *http.Request) { req.Method = http.MethodPut },
+			at:       now,
+			expected: "httpsig: invalid signature",
+		},
+		{
+			name:     "body",
+			modify:   func(req *http.Request) { req.Body = http.NoBody },
+			at:       now,
+			expected: "httpsig: invalid content digest",
+		},
+		{
+			name:     "expired",
+			modify:   func(req *http.Request) {},
+			at:       now.Add(2 * time.Minute),
+			expected: "httpsig: sig1 is too old",
+		},
+	}
+
+	for _, grid := range grids {
+		req := testRequest()
+		req.Header.Del("Content-Digest")
+		assert.NoError(t, signer.Sign(req, now))
+		assert.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", req.Header.Get("Content-Digest"))
+
+		grid.modify(req)
+		err := verifier.Verify(req, grid.at)
+		if grid.expected == "" {
+			assert.NoError(t, err, grid.name)
+		} else {
+			assert.EqualError(t, err, grid.expected, grid.name)
+		}
+	}
+
+	// A signature which does not cover a required component.
+	req := testRequest()
+	assert.NoError(t, (&Signer{KeyID: "test-key-ed25519", Algorithm: Ed25519(key), Components: []string{"@path"}}).Sign(req, now))
+	assert.EqualError(t, verifier.Verify(req, now), "httpsig: sig1 does not cover @method")
+}
+
+func TestParseSignatureInput(t *testing.T) {
+	components, params, err := parseSignatureInput(`("@method" "content-type");created=1618884473;keyid="a;b";alg="ed25519"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"@method", "content-type"}, components)
+	assert.Equal(t, map[string]string{"created": "1618884473", "keyid": "a;b", "alg": "ed25519"}, params)
+
+	_, _, err = parseSignatureInput(`"@method";created=1`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,342 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Level is the severity of a log Event.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	// LevelOff disables logging, it is the default level.
+	LevelOff
+)
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "OFF"
+}
+
+// Event is a structured log event of a request.
+//
+// A request event at LevelDebug is logged before every attempt. A response
+// event is logged after it, at LevelError if the attempt failed, LevelWarn
+// if the status is 5xx and LevelInfo otherwise.
+type Event struct {
+	Level    Level
+	Message  string
+	Method   string
+	URL      string
+	Status   int
+	Duration time.Duration
+	Bytes    int64 // The Content-Length of the request or response, -1 if unknown.
+	Attempt  int
+	Err      error
+
+	// Dump is the request or response headers and body,
+	// only set in debug mode if dumps are enabled by WithDebugDump.
+	Dump string
+}
+
+// Logger receives the log events of requests.
+type Logger interface {
+	Log(event *Event)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as Logger.
+type LoggerFunc func(event *Event)
+
+// Log calls fn(event).
+func (fn LoggerFunc) Log(event *Event) {
+	fn(event)
+}
+
+// NewStdLogger returns a Logger printing events through logger
+// as key=value pairs, the standard logger is used if nil.
+func NewStdLogger(logger *log.Logger) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &stdLogger{logger: logger}
+}
+
+type stdLogger struct {
+	logger *log.Logger
+}
+
+func (std *stdLogger) Log(event *Event) {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "[%s] %s method=%s url=%s", event.Level, event.Message, event.Method, event.URL)
+	if event.Status != 0 {
+		fmt.Fprintf(&buf, " status=%d", event.Status)
+	}
+	if event.Duration != 0 {
+		fmt.Fprintf(&buf, " duration=%s", event.Duration)
+	}
+	if event.Bytes > 0 {
+		fmt.Fprintf(&buf, " bytes=%d", event.Bytes)
+	}
+	fmt.Fprintf(&buf, " attempt=%d", event.Attempt)
+	if event.Err != nil {
+		fmt.Fprintf(&buf, " error=%q", event.Err.Error())
+	}
+	if event.Dump != "" {
+		fmt.Fprintf(&buf, "\n%s", event.Dump)
+	}
+	std.logger.Println(buf.String())
+}
+
+// WithLogger set the logger receiving the events of requests,
+// by default the standard logger through NewStdLogger.
+func WithLogger(logger Logger) Option {
+	return func(ops *options) {
+		ops.logger = logger
+	}
+}
+
+// WithLogLevel set the minimum level of logged events, LevelOff by
+// default. Requests in debug mode log from LevelDebug regardless.
+func WithLogLevel(level Level) Option {
+	return func(ops *options) {
+		ops.logLevel = level
+	}
+}
+
+// WithRedactor set the Redactor masking secrets in log events and dumps,
+// NewRedactor by default. Use nil to disable redaction.
+func WithRedactor(redactor *Redactor) Option {
+	return func(ops *options) {
+		ops.redactor = redactor
+	}
+}
+
+// WithDebugDump enable dumping the headers and the body of requests and
+// responses in debug mode, bodies are truncated to maxBody bytes.
+func WithDebugDump(maxBody int) Option {
+	return func(ops *options) {
+		ops.dump = true
+		ops.dumpMaxBody = maxBody
+	}
+}
+
+// requestLogger logs the events of the attempts of a request.
+type requestLogger struct {
+	logger      Logger
+	level       Level
+	dump        bool
+	dumpMaxBody int
+	redactor    *Redactor
+}
+
+func (r *Request) useLogger() *requestLogger {
+	level := r.owner.options.logLevel
+	if r.debug {
+		level = LevelDebug
+	}
+
+	return &requestLogger{
+		logger:      r.owner.options.logger,
+		level:       level,
+		dump:        r.owner.options.dump && r.debug,
+		dumpMaxBody: r.owner.options.dumpMaxBody,
+		redactor:    r.useCredentials().redact(r.owner.options.redactor),
+	}
+}
+
+func (l *requestLogger) enabled(level Level) bool {
+	return l.logger != nil && level >= l.level && level < LevelOff
+}
+
+func (l *requestLogger) request(req *http.Request, attempt int) {
+	if !l.enabled(LevelDebug) {
+		return
+	}
+
+	event := &Event{
+		Level:   LevelDebug,
+		Message: "request",
+		Method:  req.Method,
+		URL:     l.url(req.URL),
+		Bytes:   req.ContentLength,
+		Attempt: attempt,
+	}
+	if l.dump {
+		event.Dump = l.dumpRequest(req)
+	}
+	l.logger.Log(event)
+}
+
+func (l *requestLogger) response(req *http.Request, resp *http.Response, err error, attempt int, duration time.Duration) {
+	event := &Event{
+		Level:    LevelInfo,
+		Message:  "response",
+		Method:   req.Method,
+		URL:      l.url(req.URL),
+		Duration: duration,
+		Attempt:  attempt,
+		Err:      l.err(err),
+	}
+
+	switch {
+	case err != nil:
+		event.Level = LevelError
+	case resp.StatusCode >= http.StatusInternalServerError:
+		event.Level = LevelWarn
+	}
+	if !l.enabled(event.Level) {
+		return
+	}
+
+	if resp != nil {
+		event.Status = resp.StatusCode
+		event.Bytes = resp.ContentLength
+		if l.dump {
+			event.Dump = l.dumpResponse(resp)
+		}
+	}
+	l.logger.Log(event)
+}
+
+func (l *requestLogger) url(u *url.URL) string {
+	if l.redactor != nil {
+		u = l.redactor.URL(u)
+	}
+	return u.String()
+}
+
+func (l *requestLogger) err(err error) error {
+	if l.redactor != nil && err != nil {
+		return l.redactor.redactError(err)
+	}
+	return err
+}
+
+func (l *requestLogger) header(header http.Header) http.Header {
+	if l.redactor != nil {
+		return l.redactor.Header(header)
+	}
+	return header
+}
+
+// dumpRequest dumps the headers of req, and its body if it can be
+// read again by GetBody.
+func (l *requestLogger) dumpRequest(req *http.Request) string {
+	clone := req.Clone(req.Context())
+	clone.Header = l.header(req.Header)
+	if l.redactor != nil {
+		clone.URL = l.redactor.URL(req.URL)
+	}
+
+	head, err := httputil.DumpRequestOut(clone, false)
+	if err != nil {
+		return err.Error()
+	}
+
+	body := ""
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case req.GetBody == nil:
+		body = "[body not dumped, it can not be read again]"
+	default:
+		reader, err := req.GetBody()
+		if err != nil {
+			return err.Error()
+		}
+		defer reader.Close()
+		body = l.body(req.Header.Get("Content-Type"), reader)
+	}
+	return string(head) + body
+}
+
+// dumpResponse dumps the headers and the beginning of the body of resp,
+// the body stays readable from the start.
+func (l *requestLogger) dumpResponse(resp *http.Response) string {
+	clone := *resp
+	clone.Header = l.header(resp.Header)
+
+	head, err := httputil.DumpResponse(&clone, false)
+	if err != nil {
+		return err.Error()
+	}
+
+	var buf bytes.Buffer
+	body := l.body(resp.Header.Get("Content-Type"), io.TeeReader(resp.Body, &buf))
+	resp.Body = &readCloser{Reader: io.MultiReader(&buf, resp.Body), Closer: resp.Body}
+	return string(head) + body
+}
+
+// maxRedactBody is the maximum size of a body read to be redacted.
+const maxRedactBody = 1 << 20
+
+// body reads the body up to dumpMaxBody bytes, with secrets redacted.
+func (l *requestLogger) body(contentType string, reader io.Reader) string {
+	if l.dumpMaxBody <= 0 {
+		return ""
+	}
+
+	// A body is redacted as a whole, so it is read entirely.
+	limit, redact := l.dumpMaxBody, l.redactor != nil && l.redactor.redactsBody(contentType)
+	if redact && limit < maxRedactBody {
+		limit = maxRedactBody
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return fmt.Sprintf("[body not dumped: %s]", err)
+	}
+
+	if redact {
+		if len(data) > limit {
+			return "[body not dumped, it is too large to be redacted]"
+		}
+		if data, err = l.redactor.Body(contentType, data); err != nil {
+			return fmt.Sprintf("[body not dumped: %s]", err)
+		}
+	}
+
+	if len(data) > l.dumpMaxBody {
+		return string(data[:l.dumpMaxBody]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
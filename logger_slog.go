@@ -0,0 +1,65 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"log/slog"
+)
+
+var slogLevels = map[Level]slog.Level{
+	LevelDebug: slog.LevelDebug,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelError: slog.LevelError,
+}
+
+// NewSlogLogger returns a Logger recording events through logger
+// as slog attributes, the default slog logger is used if nil.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (s *slogLogger) Log(event *Event) {
+	attrs := []slog.Attr{
+		slog.String("method", event.Method),
+		slog.String("url", event.URL),
+		slog.Int("attempt", event.Attempt),
+	}
+	if event.Status != 0 {
+		attrs = append(attrs, slog.Int("status", event.Status))
+	}
+	if event.Duration != 0 {
+		attrs = append(attrs, slog.Duration("duration", event.Duration))
+	}
+	if event.Bytes > 0 {
+		attrs = append(attrs, slog.Int64("bytes", event.Bytes))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+	}
+	if event.Dump != "" {
+		attrs = append(attrs, slog.String("dump", event.Dump))
+	}
+
+	s.logger.LogAttrs(context.Background(), slogLevels[event.Level], event.Message, attrs...)
+}
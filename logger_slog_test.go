@@ -0,0 +1,62 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var output strings.Builder
+	handler := slog.NewTextHandler(&output, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	logger := NewSlogLogger(slog.New(handler))
+
+	logger.Log(&Event{
+		Level:    LevelWarn,
+		Message:  "response",
+		Method:   http.MethodGet,
+		URL:      "http://localhost/users",
+		Status:   http.StatusServiceUnavailable,
+		Duration: time.Second,
+		Bytes:    12,
+		Attempt:  1,
+	})
+	logger.Log(&Event{
+		Level:   LevelError,
+		Message: "response",
+		Method:  http.MethodGet,
+		URL:     "http://localhost/users",
+		Attempt: 2,
+		Err:     errors.New("connection refused"),
+	})
+
+	assert.Equal(t, "level=WARN msg=response method=GET url=http://localhost/users attempt=1 status=503 duration=1s bytes=12\n"+
+		`level=ERROR msg=response method=GET url=http://localhost/users attempt=2 error="connection refused"`+"\n", output.String())
+}
@@ -0,0 +1,184 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// eventRecorder is a Logger recording the events.
+type eventRecorder struct {
+	mux    sync.Mutex
+	events []*Event
+}
+
+func (recorder *eventRecorder) Log(event *Event) {
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+
+	recorder.events = append(recorder.events, event)
+}
+
+func (recorder *eventRecorder) messages() []string {
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+
+	messages := make([]string, 0, len(recorder.events))
+	for _, event := range recorder.events {
+		messages = append(messages, event.Level.String()+" "+event.Message)
+	}
+	return messages
+}
+
+func TestStdLogger(t *testing.T) {
+	var output strings.Builder
+	logger := NewStdLogger(log.New(&output, "", 0))
+
+	logger.Log(&Event{
+		Level:    LevelError,
+		Message:  "response",
+		Method:   http.MethodGet,
+		URL:      "http://localhost/users",
+		Duration: time.Second,
+		Bytes:    -1,
+		Attempt:  2,
+		Err:      errors.New("connection refused"),
+	})
+	logger.Log(&Event{
+		Level:   LevelDebug,
+		Message: "request",
+		Method:  http.MethodPost,
+		URL:     "http://localhost/users",
+		Bytes:   12,
+		Attempt: 1,
+		Dump:    "POST /users HTTP/1.1",
+	})
+
+	assert.Equal(t, `[ERROR] response method=GET url=http://localhost/users duration=1s attempt=2 error="connection refused"`+"\n"+
+		`[DEBUG] request method=POST url=http://localhost/users bytes=12 attempt=1`+"\n"+
+		"POST /users HTTP/1.1\n", output.String())
+}
+
+func TestLevel_String(t *testing.T) {
+	assert.Equal(t, "DEBUG", LevelDebug.String())
+	assert.Equal(t, "WARN", LevelWarn.String())
+	assert.Equal(t, "OFF", LevelOff.String())
+}
+
+func (suite *RequestSuite) Test_Logger_Level() {
+	recorder := &eventRecorder{}
+	cli := New(WithBaseURL(suite.server.URL), WithLogger(recorder), WithLogLevel(LevelInfo))
+
+	_, err := cli.Get("/users").Do()
+	assert.NoError(suite.T(), err)
+	_, err = cli.Get(suite.flakyURL("fail=1&code=500")).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"INFO response", "WARN response"}, recorder.messages())
+
+	event := recorder.events[0]
+	assert.Equal(suite.T(), http.MethodGet, event.Method)
+	assert.Equal(suite.T(), suite.server.URL+"/users", event.URL)
+	assert.Equal(suite.T(), http.StatusOK, event.Status)
+	assert.Equal(suite.T(), 1, event.Attempt)
+	assert.True(suite.T(), event.Duration > 0)
+	assert.True(suite.T(), event.Bytes > 0)
+	assert.Empty(suite.T(), event.Dump)
+
+	// Nothing is logged by default.
+	recorder = &eventRecorder{}
+	_, err = New(WithLogger(recorder)).Get(suite.server.URL + "/users").Do()
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), recorder.events)
+}
+
+func (suite *RequestSuite) Test_Logger_Error() {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	recorder := &eventRecorder{}
+	_, err := New(WithLogger(recorder), WithLogLevel(LevelError)).Get(srv.URL).Do()
+	assert.Error(suite.T(), err)
+	if assert.Equal(suite.T(), []string{"ERROR response"}, recorder.messages()) {
+		assert.Error(suite.T(), recorder.events[0].Err)
+		assert.Zero(suite.T(), recorder.events[0].Status)
+	}
+}
+
+func (suite *RequestSuite) Test_Logger_Debug() {
+	recorder := &eventRecorder{}
+	cli := New(WithLogger(recorder))
+
+	_, err := cli.Get(suite.flakyURL("fail=1")).Retry(testRetryPolicy(2, new(int32))).Debug(true).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"DEBUG request", "WARN response", "DEBUG request", "INFO response"}, recorder.messages())
+	assert.Equal(suite.T(), 1, recorder.events[1].Attempt)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, recorder.events[1].Status)
+	assert.Equal(suite.T(), 2, recorder.events[3].Attempt)
+	assert.Empty(suite.T(), recorder.events[0].Dump)
+}
+
+func (suite *RequestSuite) Test_Logger_Dump() {
+	recorder := &eventRecorder{}
+	cli := New(WithLogger(recorder), WithDebugDump(16))
+
+	echo := suite.echo(cli.Post(suite.server.URL+"/echo").
+		Header("X-Trace", "trace").
+		JSON(map[string]string{"username": "helloshaohua"}).
+		Debug(true).
+		Do())
+
+	// The dumped response body is still read entirely.
+	assert.Equal(suite.T(), `{"username":"helloshaohua"}`, echo.Body)
+
+	if assert.Len(suite.T(), recorder.events, 2) {
+		request := recorder.events[0].Dump
+		assert.Contains(suite.T(), request, "POST /echo HTTP/1.1\r\n")
+		assert.Contains(suite.T(), request, "X-Trace: trace\r\n")
+		assert.True(suite.T(), strings.HasSuffix(request, "\r\n\r\n"+`{"username":"hel...(truncated)`), request)
+
+		response := recorder.events[1].Dump
+		assert.Contains(suite.T(), response, "HTTP/1.1 200 OK\r\n")
+		assert.Contains(suite.T(), response, "Content-Type: application/json; charset=utf-8\r\n")
+		assert.True(suite.T(), strings.HasSuffix(response, "\r\n\r\n"+`{"method":"POST"...(truncated)`), response)
+	}
+
+	// Dumps are only made in debug mode.
+	recorder.events = nil
+	_, err := New(WithLogger(recorder), WithLogLevel(LevelInfo), WithDebugDump(16)).Get(suite.server.URL + "/echo").Do()
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), recorder.events, 1) {
+		assert.Empty(suite.T(), recorder.events[0].Dump)
+	}
+}
+
+func (suite *RequestSuite) Test_Logger_Dump_NotReplayable() {
+	recorder := &eventRecorder{}
+	body := struct{ *strings.Reader }{strings.NewReader("body")}
+
+	echo := suite.echo(New(WithLogger(recorder), WithDebugDump(16)).Post(suite.server.URL + "/echo").Body(body).Debug(true).Do())
+	assert.Equal(suite.T(), "body", echo.Body)
+	if assert.Len(suite.T(), recorder.events, 2) {
+		assert.Contains(suite.T(), recorder.events[0].Dump, "[body not dumped, it can not be read again]")
+	}
+}
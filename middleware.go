@@ -0,0 +1,67 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"net/http"
+)
+
+// Handler executes an http.Request, it is the function wrapped by Middleware.
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the execution of requests, e.g. to set headers,
+// log or record metrics. It calls next to continue the chain, or returns
+// without calling it to abort the request.
+//
+// Middlewares wrap the whole execution of a request, including its
+// retries, and are called in the order they were added, the client
+// ones first, then the ones of the request.
+type Middleware func(next Handler) Handler
+
+// chain wraps handler by the middlewares, the first one being the outermost.
+func chain(handler Handler, middlewares []Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Use add middlewares to the client, they are used by the requests
+// executed after this call.
+func (c *Client) Use(middlewares ...Middleware) *Client {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	// Always copy, the slice may be used by requests being built.
+	c.middlewares = append(c.middlewares[:len(c.middlewares):len(c.middlewares)], middlewares...)
+	return c
+}
+
+// Use add middlewares to the request, after the ones of the client.
+func (r *Request) Use(middlewares ...Middleware) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.middlewares = append(r.middlewares, middlewares...)
+	return r
+}
+
+func (r *Request) useMiddlewares(handler Handler) Handler {
+	r.owner.mux.RLock()
+	middlewares := r.owner.middlewares
+	r.owner.mux.RUnlock()
+
+	return chain(chain(handler, r.middlewares), middlewares)
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordMiddleware records its name into trace before and after next.
+func recordMiddleware(name string, trace *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			*trace = append(*trace, name+":before")
+			resp, err := next(req)
+			*trace = append(*trace, name+":after")
+			return resp, err
+		}
+	}
+}
+
+func (suite *RequestSuite) Test_Middleware_Order() {
+	var trace []string
+	cli := New().Use(recordMiddleware("client1", &trace), recordMiddleware("client2", &trace))
+	cli.Use(recordMiddleware("client3", &trace))
+
+	_, err := cli.Get(suite.server.URL + "/echo").Use(recordMiddleware("request", &trace)).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{
+		"client1:before", "client2:before", "client3:before", "request:before",
+		"request:after", "client3:after", "client2:after", "client1:after",
+	}, trace)
+}
+
+func (suite *RequestSuite) Test_Middleware_Header() {
+	auth := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer token")
+			return next(req)
+		}
+	}
+
+	echo := suite.echo(New().Use(auth).Get(suite.server.URL + "/echo").Do())
+	assert.Equal(suite.T(), []string{"Bearer token"}, echo.Header["Authorization"])
+}
+
+func (suite *RequestSuite) Test_Middleware_Abort() {
+	abort := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("aborted")
+		}
+	}
+
+	resp, err := New().Get(suite.server.URL + "/echo").Use(abort).Do()
+	assert.Nil(suite.T(), resp)
+	assert.EqualError(suite.T(), err, "aborted")
+
+	none := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			return nil, nil
+		}
+	}
+	_, err = New().Get(suite.server.URL + "/echo").Use(none).Do()
+	assert.Equal(suite.T(), errNoResponse, err)
+}
+
+func (suite *RequestSuite) Test_Middleware_Response() {
+	// A middleware answers the request itself, with no body.
+	noContent := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNoContent}, nil
+		}
+	}
+
+	resp, err := New().Get(suite.server.URL + "/echo").Timeout(time.Second).Use(noContent).Do()
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), http.StatusNoContent, resp.StatusCode)
+		body, err := resp.Bytes()
+		assert.NoError(suite.T(), err)
+		assert.Empty(suite.T(), body)
+	}
+}
+
+func (suite *RequestSuite) Test_Middleware_Retry() {
+	// Middlewares wrap the execution including the retries.
+	var calls int
+	count := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			calls++
+			return next(req)
+		}
+	}
+
+	resp, err := New().Use(count).Get(suite.flakyURL("fail=1")).Retry(testRetryPolicy(2, new(int32))).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "2", resp.Header.Get("X-Attempt"))
+	assert.Equal(suite.T(), 1, calls)
+}
+
+func (suite *RequestSuite) Test_Middleware_Concurrent() {
+	var mux sync.Mutex
+	var methods []string
+	record := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			mux.Lock()
+			methods = append(methods, req.Method)
+			mux.Unlock()
+			return next(req)
+		}
+	}
+
+	cli := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cli.Use(record)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := cli.Post(suite.server.URL + "/echo").Body(strings.NewReader("body")).Do()
+			assert.NoError(suite.T(), err)
+		}()
+	}
+	wg.Wait()
+
+	_, err := cli.Get(suite.server.URL + "/echo").Do()
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), methods, http.MethodGet)
+}
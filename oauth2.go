@@ -0,0 +1,135 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth2Error is the error response of a token endpoint,
+// RFC 6749 section 5.2.
+type OAuth2Error struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+}
+
+func (err *OAuth2Error) Error() string {
+	if err.Description != "" {
+		return fmt.Sprintf("httpclient: oauth2: %s: %s", err.Code, err.Description)
+	}
+	return "httpclient: oauth2: " + err.Code
+}
+
+// tokenResponse is the successful response of a token endpoint,
+// RFC 6749 section 5.1.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// requestToken posts the form params to the token endpoint by send.
+// A confidential client authenticates by HTTP Basic authentication,
+// a public one, without secret, sends its client_id.
+func requestToken(ctx context.Context, send Handler, tokenURL, clientID, clientSecret string, params url.Values) (*Token, error) {
+	if clientSecret == "" {
+		params.Set("client_id", clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	resp, err := send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		oauth2Err := &OAuth2Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(body, oauth2Err) != nil || oauth2Err.Code == "" {
+			oauth2Err.Code = http.StatusText(resp.StatusCode)
+		}
+		return nil, oauth2Err
+	}
+
+	var tokenResp tokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("httpclient: oauth2: invalid token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, errors.New("httpclient: oauth2: token response without access_token")
+	}
+
+	token := &Token{
+		AccessToken:  tokenResp.AccessToken,
+		TokenType:    tokenResp.TokenType,
+		RefreshToken: tokenResp.RefreshToken,
+	}
+	if tokenResp.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// WithOAuth2ClientCredentials set the default credentials of every request
+// to the access tokens of the OAuth2 client credentials grant, RFC 6749
+// section 4.4, authenticating the client to the token endpoint by HTTP
+// Basic authentication.
+//
+// A token is fetched on first use and cached by the client, then fetched
+// again 10 seconds before it expires, concurrent requests share a single
+// fetch. If the server responds 401, a new token is fetched and the request
+// sent again once, when its body is rewindable.
+func WithOAuth2ClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) Option {
+	return func(ops *options) {
+		ops.credentials = &credentials{
+			header: "Authorization",
+			auth: &tokenAuth{
+				fetch: func(ctx context.Context, send Handler, _ *Token) (*Token, error) {
+					params := url.Values{"grant_type": {"client_credentials"}}
+					if len(scopes) > 0 {
+						params.Set("scope", strings.Join(scopes, " "))
+					}
+					return requestToken(ctx, send, tokenURL, clientID, clientSecret, params)
+				},
+			},
+		}
+	}
+}
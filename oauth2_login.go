@@ -0,0 +1,340 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrLoginRequired is returned by a request authenticated by WithOAuth2
+// when there is no token to use or refresh, see Client.OAuth2Login.
+var ErrLoginRequired = errors.New("httpclient: oauth2: login required")
+
+// TokenStore stores the token of a login, so it is kept across runs.
+type TokenStore interface {
+	// Load returns the stored token, or nil if there is none.
+	Load() (*Token, error)
+	// Save stores token, replacing the stored one.
+	Save(token *Token) error
+}
+
+// FileTokenStore stores a token in a JSON file readable by the owner only.
+type FileTokenStore struct {
+	Path string
+}
+
+// NewFileTokenStore returns a TokenStore of the JSON file of the given path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+// Load implements the TokenStore interface.
+func (store *FileTokenStore) Load() (*Token, error) {
+	data, err := ioutil.ReadFile(store.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Save implements the TokenStore interface, the parent directory
+// is created if needed.
+func (store *FileTokenStore) Save(token *Token) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(store.Path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(store.Path, data)
+}
+
+// OAuth2Config is the configuration of the OAuth2 authorization code grant
+// with PKCE, RFC 6749 section 4.1 and RFC 7636, used by command line tools
+// to log in their user.
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string // Empty for a public client, like most tools.
+	AuthURL      string
+	TokenURL     string
+	Scopes       []string
+
+	// Store stores the token. By default it is the file
+	// httpclient/oauth2-<ClientID>.json of the user config directory,
+	// where the ClientID is escaped like a URL path segment.
+	Store TokenStore
+
+	// ListenAddr is the address of the loopback listener receiving the
+	// authorization code, "127.0.0.1:0" by default, a random port.
+	ListenAddr string
+
+	// CallbackPath is the path of the redirect URI, "/callback" by default.
+	CallbackPath string
+}
+
+func (config *OAuth2Config) store() (TokenStore, error) {
+	if config.Store != nil {
+		return config.Store, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	// The escaped ClientID has no path separator, so the file stays
+	// in the directory.
+	name := "oauth2-" + url.PathEscape(config.ClientID) + ".json"
+	return NewFileTokenStore(filepath.Join(dir, "httpclient", name)), nil
+}
+
+// WithOAuth2 set the default credentials of every request to the token
+// of the login of config, see Client.OAuth2Login.
+//
+// The stored token is refreshed by its refresh token 10 seconds before it
+// expires, or if the server responds 401, and the new token is stored.
+// If there is no token, or it cannot be refreshed, the requests fail
+// with ErrLoginRequired.
+func WithOAuth2(config *OAuth2Config) Option {
+	return func(ops *options) {
+		ops.credentials = &credentials{
+			header: "Authorization",
+			auth:   &tokenAuth{fetch: config.token},
+		}
+	}
+}
+
+// token returns the stored token, or refresh it if it is expiring
+// or stale.
+func (config *OAuth2Config) token(ctx context.Context, send Handler, stale *Token) (*Token, error) {
+	store, err := config.store()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrLoginRequired
+	}
+	if token.valid(time.Now()) && (stale == nil || token.AccessToken != stale.AccessToken) {
+		return token, nil
+	}
+	if token.RefreshToken == "" {
+		return nil, ErrLoginRequired
+	}
+
+	params := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	}
+	refreshed, err := requestToken(ctx, send, config.TokenURL, config.ClientID, config.ClientSecret, params)
+	if err != nil {
+		var oauth2Err *OAuth2Error
+		if errors.As(err, &oauth2Err) && oauth2Err.Code == "invalid_grant" {
+			return nil, ErrLoginRequired
+		}
+		return nil, err
+	}
+
+	// The server may keep the refresh token, RFC 6749 section 6.
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	if err := store.Save(refreshed); err != nil {
+		return nil, err
+	}
+	return refreshed, nil
+}
+
+// OAuth2Login logs in the user by the authorization code grant of config
+// with PKCE, and stores the token.
+//
+// It listens on the loopback address of config, then calls open with the
+// authorization URL, which usually opens it in a browser or prints it.
+// Once the user approves, the authorization server redirects the browser
+// to the listener with the authorization code, which is exchanged for the
+// token. OAuth2Login returns when the token is stored, or ctx is done.
+func (c *Client) OAuth2Login(ctx context.Context, config *OAuth2Config, open func(authURL string) error) (*Token, error) {
+	store, err := config.store()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, path := config.ListenAddr, config.CallbackPath
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	if path == "" {
+		path = "/callback"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	redirectURI := "http://" + listener.Addr().String() + path
+
+	state, err := randomURLSafe(16)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	verifier, err := randomURLSafe(32)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	codes := make(chan oauth2Callback, 1)
+	server := &http.Server{Handler: oauth2CallbackHandler(path, state, codes)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL, err := config.authURL(redirectURI, state, verifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := open(authURL); err != nil {
+		return nil, err
+	}
+
+	var callback oauth2Callback
+	select {
+	case callback = <-codes:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if callback.err != nil {
+		return nil, callback.err
+	}
+
+	params := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {callback.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	token, err := requestToken(ctx, c.client.Do, config.TokenURL, config.ClientID, config.ClientSecret, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Save(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// authURL returns the authorization URL, with the S256 code challenge
+// of verifier, RFC 7636 section 4.
+func (config *OAuth2Config) authURL(redirectURI, state, verifier string) (string, error) {
+	u, err := url.Parse(config.AuthURL)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", config.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if len(config.Scopes) > 0 {
+		query.Set("scope", strings.Join(config.Scopes, " "))
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// oauth2Callback is the authorization response received by the listener.
+type oauth2Callback struct {
+	code string
+	err  error
+}
+
+// oauth2CallbackHandler sends the first authorization response of state
+// to codes, RFC 6749 section 4.1.2. The responses of another state are
+// rejected, as they are not the ones of the login.
+func oauth2CallbackHandler(path, state string, codes chan<- oauth2Callback) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Invalid state, please try to log in again.", http.StatusBadRequest)
+			return
+		}
+
+		var callback oauth2Callback
+		switch {
+		case query.Get("error") != "":
+			callback.err = &OAuth2Error{
+				Code:        query.Get("error"),
+				Description: query.Get("error_description"),
+				URI:         query.Get("error_uri"),
+			}
+		case query.Get("code") == "":
+			callback.err = errors.New("httpclient: oauth2: authorization response without code")
+		default:
+			callback.code = query.Get("code")
+		}
+
+		select {
+		case codes <- callback:
+		default:
+		}
+
+		if callback.err != nil {
+			http.Error(w, fmt.Sprintf("Login failed: %v, you can close this window.", callback.err), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "Login succeeded, you can close this window.")
+	})
+	return mux
+}
+
+func randomURLSafe(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
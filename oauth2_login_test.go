@@ -0,0 +1,228 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coolstina/httpclient/test/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "tool", "token.json"))
+
+	token, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+
+	expected := &Token{AccessToken: "access", TokenType: "bearer", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour).Round(0)}
+	assert.NoError(t, store.Save(expected))
+
+	info, err := os.Stat(store.Path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	token, err = store.Load()
+	assert.NoError(t, err)
+	if assert.NotNil(t, token) {
+		assert.True(t, expected.Expiry.Equal(token.Expiry))
+		token.Expiry = expected.Expiry
+		assert.Equal(t, expected, token)
+	}
+}
+
+func TestOAuth2Config_Store(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	grids := []struct {
+		clientID string
+		name     string
+	}{
+		{clientID: "tool", name: "oauth2-tool.json"},
+		{clientID: "../../tool", name: "oauth2-..%2F..%2Ftool.json"},
+		{clientID: `..\tool`, name: "oauth2-..%5Ctool.json"},
+	}
+
+	for _, grid := range grids {
+		store, err := (&OAuth2Config{ClientID: grid.clientID}).store()
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "httpclient", grid.name), store.(*FileTokenStore).Path, grid.clientID)
+	}
+}
+
+func TestOAuth2Config_AuthURL(t *testing.T) {
+	config := &OAuth2Config{ClientID: "tool", AuthURL: "https://example.com/authorize?audience=api", Scopes: []string{"read", "write"}}
+
+	// The challenge is the unpadded base64url SHA-256 of the verifier, RFC 7636 section 4.2.
+	authURL, err := config.authURL("http://127.0.0.1:8000/callback", "xyz", "dBjftJeZ4CVP-mJ92K9qP2sUe4yOkZzDrJ6g3XHWQ8U")
+	assert.NoError(t, err)
+
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, "example.com", u.Host)
+	assert.Equal(t, url.Values{
+		"audience":              {"api"},
+		"response_type":         {"code"},
+		"client_id":             {"tool"},
+		"redirect_uri":          {"http://127.0.0.1:8000/callback"},
+		"state":                 {"xyz"},
+		"code_challenge":        {"bUxT8nciE3axPDWP7r_7I5moj9M7SPZiBtdqFf9qv10"},
+		"code_challenge_method": {"S256"},
+		"scope":                 {"read write"},
+	}, u.Query())
+}
+
+func TestOAuth2CallbackHandler(t *testing.T) {
+	grids := []struct {
+		query    string
+		status   int
+		code     string
+		err      string
+		received bool
+	}{
+		{query: "state=other&code=abc", status: http.StatusBadRequest},
+		{query: "state=xyz&code=abc", status: http.StatusOK, code: "abc", received: true},
+		{query: "state=xyz&error=access_denied&error_description=denied", status: http.StatusBadRequest, err: "httpclient: oauth2: access_denied: denied", received: true},
+		{query: "state=xyz", status: http.StatusBadRequest, err: "httpclient: oauth2: authorization response without code", received: true},
+	}
+
+	for _, grid := range grids {
+		codes := make(chan oauth2Callback, 1)
+		rec := httptest.NewRecorder()
+		oauth2CallbackHandler("/callback", "xyz", codes).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback?"+grid.query, nil))
+
+		assert.Equal(t, grid.status, rec.Code, grid.query)
+		select {
+		case callback := <-codes:
+			assert.True(t, grid.received, grid.query)
+			assert.Equal(t, grid.code, callback.code)
+			if grid.err != "" {
+				assert.EqualError(t, callback.err, grid.err)
+			}
+		default:
+			assert.False(t, grid.received, grid.query)
+		}
+	}
+}
+
+// browse follows the authorization URL like a browser approving the login.
+func browse(authURL string) error {
+	go func() {
+		resp, err := http.Get(authURL)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	return nil
+}
+
+func (suite *RequestSuite) oauth2Config() *OAuth2Config {
+	return &OAuth2Config{
+		ClientID: server.OAuth2ClientID,
+		AuthURL:  suite.server.URL + "/oauth2/authorize",
+		TokenURL: suite.server.URL + "/oauth2/token",
+		Scopes:   []string{"read"},
+		Store:    NewFileTokenStore(filepath.Join(suite.T().TempDir(), "token.json")),
+	}
+}
+
+func (suite *RequestSuite) Test_OAuth2Login() {
+	config := suite.oauth2Config()
+	client := New(WithBaseURL(suite.server.URL), WithOAuth2(config))
+
+	_, err := client.Get("/oauth2/resource").Do()
+	assert.True(suite.T(), errors.Is(err, ErrLoginRequired))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	token, err := client.OAuth2Login(ctx, config, browse)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NotEmpty(suite.T(), token.RefreshToken)
+
+	stored, err := config.Store.Load()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), token.AccessToken, stored.AccessToken)
+
+	echo := suite.echo(client.Get("/oauth2/resource").Do())
+	assert.Equal(suite.T(), []string{"Bearer " + token.AccessToken}, echo.Header["Authorization"])
+
+	// An expired token is refreshed by the refresh token, and stored.
+	stored.Expiry = time.Now().Add(-time.Minute)
+	assert.NoError(suite.T(), config.Store.Save(stored))
+
+	echo = suite.echo(New(WithOAuth2(config)).Get(suite.server.URL + "/oauth2/resource").Do())
+	refreshed, err := config.Store.Load()
+	assert.NoError(suite.T(), err)
+	assert.NotEqual(suite.T(), token.AccessToken, refreshed.AccessToken)
+	assert.NotEqual(suite.T(), token.RefreshToken, refreshed.RefreshToken)
+	assert.True(suite.T(), refreshed.Valid())
+	assert.Equal(suite.T(), []string{"Bearer " + refreshed.AccessToken}, echo.Header["Authorization"])
+
+	// A revoked refresh token requires to log in again.
+	refreshed.Expiry = time.Now().Add(-time.Minute)
+	refreshed.RefreshToken = "revoked"
+	assert.NoError(suite.T(), config.Store.Save(refreshed))
+
+	_, err = New(WithOAuth2(config)).Get(suite.server.URL + "/oauth2/resource").Do()
+	assert.True(suite.T(), errors.Is(err, ErrLoginRequired))
+}
+
+func (suite *RequestSuite) Test_OAuth2Login_Denied() {
+	config := suite.oauth2Config()
+	config.Scopes = []string{"denied"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := New().OAuth2Login(ctx, config, browse)
+
+	var oauth2Err *OAuth2Error
+	if assert.True(suite.T(), errors.As(err, &oauth2Err)) {
+		assert.Equal(suite.T(), "access_denied", oauth2Err.Code)
+	}
+}
+
+func (suite *RequestSuite) Test_OAuth2Login_Canceled() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var authURL string
+	_, err := New().OAuth2Login(ctx, suite.oauth2Config(), func(u string) error {
+		authURL = u
+		return nil
+	})
+	assert.Equal(suite.T(), context.DeadlineExceeded, err)
+
+	u, _ := url.Parse(authURL)
+	redirectURI, _ := url.Parse(u.Query().Get("redirect_uri"))
+	assert.Equal(suite.T(), "127.0.0.1", redirectURI.Hostname())
+	assert.Equal(suite.T(), "/callback", redirectURI.Path)
+
+	// The listener is closed.
+	_, err = http.Get(redirectURI.String())
+	assert.Error(suite.T(), err)
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolstina/httpclient/test/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenURL returns the URL of the token endpoint, counting
+// the token requests of the test, see server.OAuth2TokenFetches.
+func (suite *RequestSuite) tokenURL(query string) string {
+	return suite.server.URL + "/oauth2/token?key=" + url.QueryEscape(suite.T().Name()) + query
+}
+
+func (suite *RequestSuite) tokenFetches() int {
+	return server.OAuth2TokenFetches(suite.T().Name())
+}
+
+func (suite *RequestSuite) Test_OAuth2ClientCredentials() {
+	client := New(
+		WithBaseURL(suite.server.URL),
+		WithOAuth2ClientCredentials(suite.tokenURL(""), server.OAuth2ClientID, server.OAuth2ClientSecret, "read", "write"),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			echo := suite.echo(client.Get("/oauth2/resource").Do())
+			assert.True(suite.T(), strings.HasPrefix(echo.Header["Authorization"][0], "Bearer "))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(suite.T(), 1, suite.tokenFetches())
+}
+
+func (suite *RequestSuite) Test_OAuth2ClientCredentials_Refresh() {
+	// The token expires in 11 seconds, so it is fetched again after
+	// a second, 10 seconds before it expires.
+	client := New(
+		WithBaseURL(suite.server.URL),
+		WithOAuth2ClientCredentials(suite.tokenURL("&expires_in=11"), server.OAuth2ClientID, server.OAuth2ClientSecret),
+	)
+
+	first := suite.echo(client.Get("/oauth2/resource").Do())
+	suite.echo(client.Get("/oauth2/resource").Do())
+	assert.Equal(suite.T(), 1, suite.tokenFetches())
+
+	time.Sleep(1100 * time.Millisecond)
+	second := suite.echo(client.Get("/oauth2/resource").Do())
+	assert.Equal(suite.T(), 2, suite.tokenFetches())
+	assert.NotEqual(suite.T(), first.Header["Authorization"], second.Header["Authorization"])
+}
+
+func (suite *RequestSuite) Test_OAuth2ClientCredentials_Revoked() {
+	client := New(
+		WithBaseURL(suite.server.URL),
+		WithOAuth2ClientCredentials(suite.tokenURL(""), server.OAuth2ClientID, server.OAuth2ClientSecret),
+	)
+
+	echo := suite.echo(client.Get("/oauth2/resource").Do())
+	token := strings.TrimPrefix(echo.Header["Authorization"][0], "Bearer ")
+	resp, err := New().Post(suite.server.URL + "/oauth2/revoke").Form(Params{{Key: "token", Value: token}}).Do()
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), resp.IsSuccess())
+
+	// The request rejected with the revoked token is sent again with a new one.
+	echo = suite.echo(client.Post("/oauth2/resource").JSON(map[string]int{"id": 1}).Do())
+	assert.NotEqual(suite.T(), "Bearer "+token, echo.Header["Authorization"][0])
+	assert.Equal(suite.T(), `{"id":1}`, echo.Body)
+	assert.Equal(suite.T(), 2, suite.tokenFetches())
+}
+
+func (suite *RequestSuite) Test_OAuth2ClientCredentials_Error() {
+	client := New(
+		WithBaseURL(suite.server.URL),
+		WithOAuth2ClientCredentials(suite.server.URL+"/oauth2/token", server.OAuth2ClientID, "wrong"),
+	)
+
+	_, err := client.Get("/oauth2/resource").Do()
+	var oauth2Err *OAuth2Error
+	if assert.True(suite.T(), errors.As(err, &oauth2Err)) {
+		assert.Equal(suite.T(), http.StatusUnauthorized, oauth2Err.StatusCode)
+		assert.Equal(suite.T(), "invalid_client", oauth2Err.Code)
+		assert.Equal(suite.T(), "httpclient: oauth2: invalid_client", err.Error())
+	}
+}
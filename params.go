@@ -14,6 +14,11 @@
 
 package httpclient
 
+import (
+	"net/url"
+	"strings"
+)
+
 // Param is a single URL parameter, consisting of a key and a value.
 type Param struct {
 	Key   string
@@ -78,3 +83,18 @@ func (ps Params) Removes(names ParamKeys) Params {
 	}
 	return r
 }
+
+// Encode encodes the parameters into URL encoded form ("bar=baz&foo=quux"),
+// keeping the order of the slice. Repeated keys are encoded once per Param.
+func (ps Params) Encode() string {
+	var buf strings.Builder
+	for _, entry := range ps {
+		if buf.Len() > 0 {
+			buf.WriteByte('&')
+		}
+		buf.WriteString(url.QueryEscape(entry.Key))
+		buf.WriteByte('=')
+		buf.WriteString(url.QueryEscape(entry.Value))
+	}
+	return buf.String()
+}
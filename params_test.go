@@ -59,3 +59,35 @@ func TestRemoves(t *testing.T) {
 	params = params.Removes(ParamKeys{"address", "sex"})
 	assert.Len(t, params, 1)
 }
+
+func TestEncode(t *testing.T) {
+	grids := []struct {
+		params   Params
+		expected string
+	}{
+		{
+			params:   Params{},
+			expected: "",
+		},
+		{
+			params: Params{
+				{Key: "username", Value: "helloshaohua"},
+				{Key: "address", Value: "北京"},
+				{Key: "tag", Value: "a"},
+				{Key: "tag", Value: "b&c"},
+			},
+			expected: "username=helloshaohua&address=%E5%8C%97%E4%BA%AC&tag=a&tag=b%26c",
+		},
+		{
+			params: Params{
+				{Key: "z", Value: "1"},
+				{Key: "a b", Value: "x=y"},
+			},
+			expected: "z=1&a+b=x%3Dy",
+		},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, grid.params.Encode())
+	}
+}
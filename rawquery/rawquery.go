@@ -29,13 +29,14 @@ func (q Query) String() string {
 	return fmt.Sprintf("%s:%s", q.Field, q.Value)
 }
 
-// ParseRawQuery parse raw query into the Query slice.
+// ParseRawQuery parse raw query into the Query slice, the values
+// are kept encoded. A value may contain '=', e.g. a base64 one.
 func ParseRawQuery(rawquery string) []Query {
 	slice := strings.Split(rawquery, "&")
 	parse := make([]Query, 0, len(slice))
 
 	for _, pair := range slice {
-		pairs := strings.Split(pair, "=")
+		pairs := strings.SplitN(pair, "=", 2)
 		if len(pairs) == 2 {
 			parse = append(parse, Query{
 				Field: pairs[0],
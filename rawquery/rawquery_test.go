@@ -81,6 +81,19 @@ func TestParseRawQuery(t *testing.T) {
 				},
 			},
 		},
+		{
+			rawquery: `sign=YWJj==&flag&name=hello%20world`,
+			expected: []Query{
+				{
+					Field: "sign",
+					Value: "YWJj==",
+				},
+				{
+					Field: "name",
+					Value: "hello%20world",
+				},
+			},
+		},
 	}
 
 	for _, grid := range grids {
@@ -0,0 +1,234 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces the secrets masked by a Redactor.
+const Redacted = "REDACTED"
+
+// Redactor masks secrets in the URLs, headers and bodies written to
+// log events and dumps.
+//
+// By default it masks the Authorization, Proxy-Authorization, Cookie and
+// Set-Cookie headers and the token, access_token and password parameters.
+// A Redactor must not be modified once used by a Client.
+type Redactor struct {
+	headers map[string]bool
+	params  map[string]bool
+	paths   [][]string
+}
+
+// NewRedactor returns a Redactor masking the default secrets.
+func NewRedactor() *Redactor {
+	redactor := &Redactor{
+		headers: make(map[string]bool),
+		params:  make(map[string]bool),
+	}
+	redactor.AddHeaders("Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie")
+	redactor.AddParams("token", "access_token", "password")
+	return redactor
+}
+
+func (redactor *Redactor) clone() *Redactor {
+	clone := &Redactor{
+		headers: make(map[string]bool, len(redactor.headers)),
+		params:  make(map[string]bool, len(redactor.params)),
+		paths:   append([][]string(nil), redactor.paths...),
+	}
+	for key := range redactor.headers {
+		clone.headers[key] = true
+	}
+	for key := range redactor.params {
+		clone.params[key] = true
+	}
+	return clone
+}
+
+// AddHeaders masks the headers of the given names.
+func (redactor *Redactor) AddHeaders(names ...string) *Redactor {
+	for _, name := range names {
+		redactor.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	return redactor
+}
+
+// AddParams masks the query and form parameters of the given keys,
+// case insensitively.
+func (redactor *Redactor) AddParams(keys ...string) *Redactor {
+	for _, key := range keys {
+		redactor.params[strings.ToLower(key)] = true
+	}
+	return redactor
+}
+
+// AddJSONPaths masks the values of JSON bodies at the given paths.
+// A path is a dot separated list of object keys or array indexes,
+// where * matches any key or index, e.g. "user.password" or "items.*.token".
+func (redactor *Redactor) AddJSONPaths(paths ...string) *Redactor {
+	for _, path := range paths {
+		redactor.paths = append(redactor.paths, strings.Split(path, "."))
+	}
+	return redactor
+}
+
+// URL returns a copy of u, with the password and the masked query
+// parameters redacted.
+func (redactor *Redactor) URL(u *url.URL) *url.URL {
+	redacted := *u
+	if _, ok := u.User.Password(); ok {
+		redacted.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	if u.RawQuery != "" {
+		redacted.RawQuery = redactor.query(u.RawQuery)
+	}
+	return &redacted
+}
+
+// query masks the parameters of a URL encoded query, keeping its order.
+func (redactor *Redactor) query(raw string) string {
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key := pair
+		if idx := strings.IndexByte(pair, '='); idx >= 0 {
+			key = pair[:idx]
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil && redactor.params[strings.ToLower(unescaped)] {
+			pairs[i] = key + "=" + Redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Header returns a copy of header, with the masked headers redacted.
+func (redactor *Redactor) Header(header http.Header) http.Header {
+	redacted := header.Clone()
+	for key, values := range redacted {
+		if redactor.headers[http.CanonicalHeaderKey(key)] {
+			for i := range values {
+				values[i] = Redacted
+			}
+		}
+	}
+	return redacted
+}
+
+// Params returns a copy of params, with the masked parameters redacted.
+func (redactor *Redactor) Params(params Params) Params {
+	redacted := make(Params, 0, len(params))
+	for _, param := range params {
+		if redactor.params[strings.ToLower(param.Key)] {
+			param.Value = Redacted
+		}
+		redacted = append(redacted, param)
+	}
+	return redacted
+}
+
+// Body returns the body of the given Content-Type with the secrets redacted,
+// the masked parameters of a form body and the masked paths of a JSON body.
+// Other bodies are returned as is.
+func (redactor *Redactor) Body(contentType string, body []byte) ([]byte, error) {
+	switch mediaType(contentType) {
+	case "application/x-www-form-urlencoded":
+		return []byte(redactor.query(string(body))), nil
+	case "json":
+		if len(redactor.paths) == 0 {
+			return body, nil
+		}
+
+		var v interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return nil, err
+		}
+		for _, path := range redactor.paths {
+			v = redactJSON(v, path)
+		}
+		return json.Marshal(v)
+	}
+	return body, nil
+}
+
+// redactsBody reports whether bodies of the Content-Type are redacted,
+// which requires them to be read entirely.
+func (redactor *Redactor) redactsBody(contentType string) bool {
+	switch mediaType(contentType) {
+	case "application/x-www-form-urlencoded":
+		return true
+	case "json":
+		return len(redactor.paths) > 0
+	}
+	return false
+}
+
+// redactError returns err with its URL redacted, if it is a *url.Error.
+func (redactor *Redactor) redactError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	u, parseErr := url.Parse(urlErr.URL)
+	if parseErr != nil {
+		return err
+	}
+	return &url.Error{Op: urlErr.Op, URL: redactor.URL(u).String(), Err: urlErr.Err}
+}
+
+// mediaType returns the media type of contentType,
+// "json" for every JSON media type.
+func mediaType(contentType string) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if media == "application/json" || strings.HasSuffix(media, "+json") {
+		return "json"
+	}
+	return media
+}
+
+func redactJSON(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return Redacted
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if path[0] == "*" || path[0] == key {
+				value[key] = redactJSON(item, path[1:])
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			if path[0] == "*" || path[0] == strconv.Itoa(i) {
+				value[i] = redactJSON(item, path[1:])
+			}
+		}
+	}
+	return v
+}
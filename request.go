@@ -0,0 +1,400 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is a single HTTP request built by a Client.
+// Every call of Get, Post, etc. returns a new Request, so requests
+// built by different goroutines never share state. A Request is
+// executed by the Client that built it.
+type Request struct {
+	owner       *Client
+	insecure    bool
+	method      string
+	timeout     time.Duration
+	url         string
+	header      http.Header
+	body        io.Reader // Use POST/PUT/PATCH/DELETE
+	multipart   *multipartBody
+	queryParams Params
+	retry       *RetryPolicy // Overrides the client policy if set.
+	credentials *credentials // Overrides the client credentials if set.
+	middlewares []Middleware
+	debug       bool
+	ctx         context.Context
+	err         error // Returned by Do, set by a failed setter.
+	mux         sync.Mutex
+}
+
+func newRequest(owner *Client, method string, url string) *Request {
+	// Request headers start from the client default headers,
+	// so the ones set on the request take precedence.
+	header := owner.options.header.Clone()
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", owner.options.userAgent)
+	}
+
+	return &Request{
+		owner:  owner,
+		method: method,
+		url:    url,
+		header: header,
+	}
+}
+
+// Header set the header key to value, replacing any existing values,
+// including the client default ones.
+func (r *Request) Header(key, value string) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.header.Set(key, value)
+	return r
+}
+
+// Headers set every header of the map, see Header.
+func (r *Request) Headers(header map[string]string) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for key, value := range header {
+		r.header.Set(key, value)
+	}
+	return r
+}
+
+// AddHeader add value to the header key, keeping existing values.
+func (r *Request) AddHeader(key, value string) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.header.Add(key, value)
+	return r
+}
+
+// DelHeader delete the values of the header key,
+// including the client default ones.
+func (r *Request) DelHeader(key string) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.header.Del(key)
+	return r
+}
+
+// UserAgent set the User-Agent header.
+func (r *Request) UserAgent(userAgent string) *Request {
+	return r.Header("User-Agent", userAgent)
+}
+
+func (r *Request) Body(body io.Reader) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.body = body
+	return r
+}
+
+func (r *Request) BodyWithJSON(s string) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.body = strings.NewReader(s)
+	return r
+}
+
+func (r *Request) QueryParams(params Params) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.queryParams = params
+	return r
+}
+
+func (r *Request) Timeout(wait time.Duration) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.timeout = wait
+	return r
+}
+
+// InsecureSkipVerify skip the verification of the server certificate,
+// using a copy of the client transport. Do returns an error if the
+// transport set by WithTransport is not an *http.Transport.
+func (r *Request) InsecureSkipVerify(skip bool) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.owner.client.Transport.(*http.Transport); skip && !ok {
+		r.err = errInsecureTransport
+		return r
+	}
+	r.insecure = skip
+	return r
+}
+
+func (r *Request) Debug(debug bool) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.debug = debug
+	return r
+}
+
+// WithContext set the context used by Do, see DoContext.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.ctx = ctx
+	return r
+}
+
+// Do execute the request with the context set by WithContext,
+// or the background context if none is set.
+func (r *Request) Do() (*Response, error) {
+	r.mux.Lock()
+	ctx := r.ctx
+	r.mux.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.DoContext(ctx)
+}
+
+// DoContext execute the request with ctx. The request is cancelled
+// when ctx is done or the timeout expires, whichever comes first,
+// which also closes the underlying connection.
+//
+// The request lock only guards building the http.Request from the
+// configuration, the round-trip runs without it, so requests are
+// executed in parallel.
+func (r *Request) DoContext(ctx context.Context) (*Response, error) {
+	call, err := r.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute http request.
+	start := time.Now()
+	resp, err := call.do()
+	if err != nil {
+		if call.cancel != nil {
+			call.cancel()
+		}
+		return nil, err
+	}
+
+	// The timeout also covers reading the body,
+	// so it is only cancelled once the body is closed.
+	if call.cancel != nil {
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: call.cancel}
+	}
+
+	return newResponse(resp, time.Since(start)), nil
+}
+
+var errInsecureTransport = errors.New("httpclient: InsecureSkipVerify requires an *http.Transport")
+
+var errNoResponse = errors.New("httpclient: middleware returned neither a response nor an error")
+
+// call is a snapshot of a request, ready to be executed.
+type call struct {
+	req     *http.Request
+	client  *http.Client
+	auth    authenticator
+	retry   *RetryPolicy
+	logger  *requestLogger
+	handler Handler // The middleware chain around send.
+	cancel  context.CancelFunc
+}
+
+func (c *call) do() (*http.Response, error) {
+	resp, err := c.handler(c.req)
+	if resp == nil && err == nil {
+		return nil, errNoResponse
+	}
+	if resp != nil && resp.Body == nil {
+		// A response made by a middleware may have no body.
+		resp.Body = http.NoBody
+	}
+	return resp, err
+}
+
+func (c *call) send(req *http.Request) (*http.Response, error) {
+	if c.retry != nil {
+		return c.retry.do(c.attempt, req)
+	}
+	return c.attempt(req, 1)
+}
+
+// attempt execute a single attempt of the request.
+func (c *call) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	c.logger.request(req, attempt)
+
+	start := time.Now()
+	resp, err := c.roundTrip(req)
+	c.logger.response(req, resp, err, attempt, time.Since(start))
+	return resp, err
+}
+
+// roundTrip execute req by the client, authenticated by the
+// authenticator of the credentials if any.
+func (c *call) roundTrip(req *http.Request) (*http.Response, error) {
+	if c.auth != nil {
+		return c.auth.do(c.clientDo, req)
+	}
+	return c.clientDo(req)
+}
+
+func (c *call) clientDo(req *http.Request) (*http.Response, error) {
+	if c.client.Jar != nil {
+		// http.Client adds the cookies of the jar to the header of req,
+		// which would be sent twice when req is sent again.
+		req = req.Clone(req.Context())
+	}
+	return c.client.Do(req)
+}
+
+// build snapshots the configuration of the request into a call.
+func (r *Request) build(ctx context.Context) (*call, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// New request, a HEAD request never reads the body.
+	var body io.Reader
+	switch {
+	case r.method == http.MethodHead:
+	case r.multipart != nil:
+		body = r.multipart.reader()
+	default:
+		body = r.body
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, joinURL(r.owner.options.baseURL, r.url), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use request headers.
+	req.Header = r.header.Clone()
+
+	// Use credentials.
+	req = r.useAuth(req)
+
+	// Use multipart body.
+	r.useMultipart(req)
+
+	// Use query parameters,
+	// if request Method is GET and call QueryParams method,
+	// then use
+	r.useQueryParams(req)
+
+	// Use timeout.
+	req, cancel := r.useTimeout(req)
+
+	c := &call{
+		req:    req,
+		client: r.useClient(),
+		auth:   r.useAuthenticator(),
+		retry:  r.useRetry(),
+		logger: r.useLogger(),
+		cancel: cancel,
+	}
+	c.handler = r.useMiddlewares(c.send)
+	return c, nil
+}
+
+func (r *Request) useMultipart(req *http.Request) {
+	if r.multipart != nil && req.Body != nil {
+		req.Header.Set("Content-Type", r.multipart.contentType())
+
+		if r.multipart.replayable() {
+			multipart := r.multipart
+			req.GetBody = func() (io.ReadCloser, error) {
+				return multipart.reader(), nil
+			}
+		}
+	}
+}
+
+func (r *Request) useQueryParams(req *http.Request) {
+	params := r.queryParams
+	if creds := r.useCredentials(); creds != nil && creds.query != "" {
+		params = append(params[:len(params):len(params)], Param{Key: creds.query, Value: creds.value})
+	}
+
+	if params != nil {
+		query := req.URL.Query()
+
+		for _, param := range params {
+			query.Add(param.Key, param.Value)
+		}
+
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+// useClient returns the http.Client of the owner, or its
+// insecure copy if the request skips the certificate verification.
+func (r *Request) useClient() *http.Client {
+	if r.insecure {
+		return r.owner.insecureClient()
+	}
+	return r.owner.client
+}
+
+func (r *Request) useTimeout(req *http.Request) (*http.Request, context.CancelFunc) {
+	timeout := r.timeout
+	if timeout == 0 {
+		timeout = r.owner.options.timeout
+	}
+
+	if timeout != 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		return req.WithContext(ctx), cancel
+	}
+	return req, nil
+}
+
+// cancelBody cancel the context of the request when the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// JSON set the body to the JSON encoding of v and the Content-Type
+// header to application/json. The encoder can be configured by the
+// given functions, e.g. to call SetEscapeHTML(false). An encoding
+// error is returned by Do.
+//
+// The body can be replayed, e.g. on redirects and retries.
+func (r *Request) JSON(v interface{}, configure ...func(enc *json.Encoder)) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, fn := range configure {
+		fn(enc)
+	}
+
+	if err := enc.Encode(v); err != nil {
+		r.err = err
+		return r
+	}
+
+	// A bytes.Reader body makes http.NewRequest set GetBody.
+	r.body = bytes.NewReader(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// Form set the body to the URL encoded params, in the order of the
+// slice, and the Content-Type header to application/x-www-form-urlencoded.
+// Repeated keys are sent once per Param, the same as QueryParams.
+func (r *Request) Form(params Params) *Request {
+	return r.form(params.Encode())
+}
+
+// FormValues set the body to the URL encoded values, see Form.
+func (r *Request) FormValues(values url.Values) *Request {
+	return r.form(values.Encode())
+}
+
+func (r *Request) form(encoded string) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.body = strings.NewReader(encoded)
+	r.header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *RequestSuite) Test_JSON() {
+	user := map[string]string{
+		"username": "json",
+		"sex":      "female",
+		"mobile":   "+8613700001000",
+	}
+
+	// The mock API binds the body by its Content-Type.
+	resp, err := New().Post(suite.server.URL + "/users").JSON(user).Do()
+	assert.NoError(suite.T(), err)
+	body, err := resp.String()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"result":"add user successfully"}`, body)
+
+	echo := suite.echo(New().Post(suite.server.URL + "/echo").JSON(user).Do())
+	assert.Equal(suite.T(), []string{"application/json"}, echo.Header["Content-Type"])
+	assert.JSONEq(suite.T(), `{"username":"json","sex":"female","mobile":"+8613700001000"}`, echo.Body)
+}
+
+func (suite *RequestSuite) Test_JSON_Encoder() {
+	v := map[string]string{"url": "/users?a=1&b=2"}
+
+	echo := suite.echo(New().Post(suite.server.URL + "/echo").JSON(v).Do())
+	assert.Equal(suite.T(), `{"url":"/users?a=1\u0026b=2"}`, echo.Body)
+
+	echo = suite.echo(New().Post(suite.server.URL+"/echo").JSON(v, func(enc *json.Encoder) {
+		enc.SetEscapeHTML(false)
+	}).Do())
+	assert.Equal(suite.T(), `{"url":"/users?a=1&b=2"}`, echo.Body)
+}
+
+func (suite *RequestSuite) Test_JSON_Error() {
+	resp, err := New().Post(suite.server.URL + "/echo").JSON(make(chan int)).Do()
+	assert.Nil(suite.T(), resp)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RequestSuite) Test_JSON_Redirect() {
+	// A 307 redirect replays the body through GetBody.
+	echo := suite.echo(New().Post(suite.server.URL + "/redirect").JSON(map[string]int{"id": 1}).Do())
+	assert.Equal(suite.T(), http.MethodPost, echo.Method)
+	assert.Equal(suite.T(), "/echo", echo.Path)
+	assert.Equal(suite.T(), `{"id":1}`, echo.Body)
+}
+
+func (suite *RequestSuite) Test_Form() {
+	params := Params{
+		{Key: "username", Value: "helloshaohua"},
+		{Key: "tag", Value: "b"},
+		{Key: "tag", Value: "a"},
+	}
+
+	echo := suite.echo(New().Post(suite.server.URL + "/echo").Form(params).Do())
+	assert.Equal(suite.T(), []string{"application/x-www-form-urlencoded"}, echo.Header["Content-Type"])
+	assert.Equal(suite.T(), "username=helloshaohua&tag=b&tag=a", echo.Body)
+}
+
+func (suite *RequestSuite) Test_FormValues() {
+	values := url.Values{}
+	values.Add("username", "helloshaohua")
+	values.Add("tag", "a")
+	values.Add("tag", "b")
+
+	echo := suite.echo(New().Post(suite.server.URL + "/echo").FormValues(values).Do())
+	assert.Equal(suite.T(), []string{"application/x-www-form-urlencoded"}, echo.Header["Content-Type"])
+	assert.Equal(suite.T(), "tag=a&tag=b&username=helloshaohua", echo.Body)
+}
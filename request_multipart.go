@@ -0,0 +1,217 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// multipartPart is a part of a multipart/form-data body, open returns
+// its content each time the body is written.
+type multipartPart struct {
+	header     textproto.MIMEHeader
+	open       func() (io.ReadCloser, error)
+	replayable bool
+}
+
+// multipartBody is a multipart/form-data body, it is streamed through
+// an io.Pipe, so the parts are never buffered in memory.
+type multipartBody struct {
+	boundary string
+	parts    []*multipartPart
+}
+
+func newMultipartBody() *multipartBody {
+	return &multipartBody{boundary: multipart.NewWriter(ioutil.Discard).Boundary()}
+}
+
+func (m *multipartBody) contentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// replayable reports whether every part can be written again,
+// which is not the case for parts read from an io.Reader.
+func (m *multipartBody) replayable() bool {
+	for _, part := range m.parts {
+		if !part.replayable {
+			return false
+		}
+	}
+	return true
+}
+
+// reader returns the body, written by a goroutine started on the first
+// Read, which stops once the body is written or the reader is closed.
+// A request which is never sent leaves no goroutine behind.
+func (m *multipartBody) reader() io.ReadCloser {
+	parts := append([]*multipartPart(nil), m.parts...)
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	_ = writer.SetBoundary(m.boundary) // Validated by Request.MultipartBoundary.
+
+	return &multipartReader{
+		PipeReader: pr,
+		start: func() {
+			go func() {
+				pw.CloseWithError(m.write(writer, parts))
+			}()
+		},
+	}
+}
+
+// multipartReader is the reader side of the multipart/form-data body pipe,
+// it starts the writer on the first Read, unless it was closed before.
+type multipartReader struct {
+	*io.PipeReader
+	once  sync.Once
+	start func()
+}
+
+func (r *multipartReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	return r.PipeReader.Read(p)
+}
+
+func (r *multipartReader) Close() error {
+	r.once.Do(func() {})
+	return r.PipeReader.Close()
+}
+
+func (m *multipartBody) write(writer *multipart.Writer, parts []*multipartPart) error {
+	for _, part := range parts {
+		w, err := writer.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+
+		body, err := part.open()
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func formDataHeader(field, filename, contentType string) textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader)
+	if filename == "" {
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(field)))
+		return header
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field), quoteEscaper.Replace(filename)))
+	header.Set("Content-Type", contentType)
+	return header
+}
+
+// Field add a form field to the multipart/form-data body.
+//
+// Once a field, file or part is added, the body is multipart/form-data
+// and the body set by Body, JSON or Form is ignored.
+func (r *Request) Field(name, value string) *Request {
+	return r.part(&multipartPart{
+		header: formDataHeader(name, "", ""),
+		open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(value)), nil
+		},
+		replayable: true,
+	})
+}
+
+// File add the file of path to the multipart/form-data body, the
+// Content-Type of the part is guessed from its extension. The file
+// is opened and streamed when the request is sent, see Field.
+func (r *Request) File(field, path string) *Request {
+	return r.part(&multipartPart{
+		header: formDataHeader(field, filepath.Base(path), mime.TypeByExtension(filepath.Ext(path))),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+		replayable: true,
+	})
+}
+
+// FileReader add a file read from reader to the multipart/form-data body,
+// contentType defaults to application/octet-stream. The reader is
+// streamed when the request is sent, see Field.
+func (r *Request) FileReader(field, filename string, reader io.Reader, contentType string) *Request {
+	return r.Part(formDataHeader(field, filename, contentType), reader)
+}
+
+// Part add a part with custom headers to the multipart/form-data body.
+// The body is streamed when the request is sent, see Field.
+func (r *Request) Part(header textproto.MIMEHeader, body io.Reader) *Request {
+	return r.part(&multipartPart{
+		header: header,
+		open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(body), nil
+		},
+	})
+}
+
+// MultipartBoundary set the boundary of the multipart/form-data body,
+// which is random by default. An invalid boundary is returned by Do.
+func (r *Request) MultipartBoundary(boundary string) *Request {
+	if err := multipart.NewWriter(ioutil.Discard).SetBoundary(boundary); err != nil {
+		r.mux.Lock()
+		defer r.mux.Unlock()
+
+		r.err = err
+		return r
+	}
+
+	return r.withMultipart(func(m *multipartBody) {
+		m.boundary = boundary
+	})
+}
+
+func (r *Request) part(part *multipartPart) *Request {
+	return r.withMultipart(func(m *multipartBody) {
+		m.parts = append(m.parts, part)
+	})
+}
+
+func (r *Request) withMultipart(fn func(m *multipartBody)) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.multipart == nil {
+		r.multipart = newMultipartBody()
+	}
+	fn(r.multipart)
+	return r
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coolstina/httpclient/test/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func (suite *RequestSuite) upload(resp *Response, err error) []server.UploadPart {
+	var parts []server.UploadPart
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+		assert.NoError(suite.T(), resp.JSON(&parts))
+	}
+	return parts
+}
+
+func (suite *RequestSuite) tempFile(name, content string) string {
+	dir, err := ioutil.TempDir("", "httpclient")
+	assert.NoError(suite.T(), err)
+	suite.T().Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	assert.NoError(suite.T(), ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func (suite *RequestSuite) Test_Multipart() {
+	path := suite.tempFile("users.json", `[{"username":"helloshaohua"}]`)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="custom"`)
+	header.Set("X-Part", "custom")
+
+	parts := suite.upload(New().Post(suite.server.URL+"/upload").
+		Field("username", "helloshaohua").
+		File("users", path).
+		FileReader("avatar", `my "avatar".png`, strings.NewReader("png"), "image/png").
+		FileReader("raw", "raw.bin", strings.NewReader("raw"), "").
+		Part(header, strings.NewReader("custom")).
+		Do())
+
+	expected := []server.UploadPart{
+		{Name: "username"},
+		{Name: "users", Filename: "users.json", ContentType: "application/json"},
+		{Name: "avatar", Filename: `my "avatar".png`, ContentType: "image/png"},
+		{Name: "raw", Filename: "raw.bin", ContentType: "application/octet-stream"},
+		{Name: "custom"},
+	}
+	if assert.Len(suite.T(), parts, len(expected)) {
+		for i, part := range expected {
+			assert.Equal(suite.T(), part.Name, parts[i].Name)
+			assert.Equal(suite.T(), part.Filename, parts[i].Filename)
+			assert.Equal(suite.T(), part.ContentType, parts[i].ContentType)
+		}
+		assert.Equal(suite.T(), sha256Hex("helloshaohua"), parts[0].SHA256)
+		assert.Equal(suite.T(), sha256Hex(`[{"username":"helloshaohua"}]`), parts[1].SHA256)
+		assert.Equal(suite.T(), sha256Hex("png"), parts[2].SHA256)
+		assert.Equal(suite.T(), []string{"custom"}, parts[4].Header["X-Part"])
+	}
+}
+
+func (suite *RequestSuite) Test_MultipartBoundary() {
+	echo := suite.echo(New().Post(suite.server.URL+"/echo").
+		MultipartBoundary("boundary").
+		Field("username", "helloshaohua").
+		FileReader("avatar", "avatar.png", strings.NewReader("png"), "image/png").
+		Do())
+
+	expected := "--boundary\r\n" +
+		"Content-Disposition: form-data; name=\"username\"\r\n\r\n" +
+		"helloshaohua\r\n" +
+		"--boundary\r\n" +
+		"Content-Disposition: form-data; name=\"avatar\"; filename=\"avatar.png\"\r\n" +
+		"Content-Type: image/png\r\n\r\n" +
+		"png\r\n" +
+		"--boundary--\r\n"
+	assert.Equal(suite.T(), []string{"multipart/form-data; boundary=boundary"}, echo.Header["Content-Type"])
+	assert.Equal(suite.T(), expected, echo.Body)
+
+	_, err := New().Post(suite.server.URL+"/echo").MultipartBoundary("bad boundary ").Field("a", "b").Do()
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RequestSuite) Test_Multipart_FileNotFound() {
+	resp, err := New().Post(suite.server.URL+"/upload").File("file", "test/data/not_found.txt").Do()
+	assert.Nil(suite.T(), resp)
+	assert.True(suite.T(), os.IsNotExist(errors.Unwrap(errors.Unwrap(err))), err)
+}
+
+func (suite *RequestSuite) Test_Multipart_Redirect() {
+	path := suite.tempFile("users.txt", "helloshaohua")
+
+	// Fields and files are written again when the body is replayed.
+	parts := suite.upload(New().Post(suite.server.URL+"/redirect?to=/upload").
+		Field("username", "helloshaohua").
+		File("users", path).
+		Do())
+	if assert.Len(suite.T(), parts, 2) {
+		assert.Equal(suite.T(), sha256Hex("helloshaohua"), parts[1].SHA256)
+	}
+}
+
+// gateReader yields size bytes, but blocks after the first chunk
+// until gate is closed.
+type gateReader struct {
+	size int64
+	read int64
+	gate chan struct{}
+}
+
+func (reader *gateReader) Read(p []byte) (int, error) {
+	if reader.read >= reader.size {
+		return 0, io.EOF
+	}
+	if reader.read > 0 {
+		select {
+		case <-reader.gate:
+		case <-time.After(5 * time.Second):
+			return 0, errors.New("the body is buffered before being sent")
+		}
+	}
+
+	if int64(len(p)) > reader.size-reader.read {
+		p = p[:reader.size-reader.read]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	reader.read += int64(len(p))
+	return len(p), nil
+}
+
+func (suite *RequestSuite) Test_Multipart_Streaming() {
+	reader := &gateReader{size: 16 << 20, gate: make(chan struct{})}
+
+	// The server opens the gate once it receives the first bytes of the
+	// file, which only happens if the body is streamed.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mr, err := req.MultipartReader()
+		if !assert.NoError(suite.T(), err) {
+			return
+		}
+		part, err := mr.NextPart()
+		if !assert.NoError(suite.T(), err) {
+			return
+		}
+
+		_, err = io.ReadFull(part, make([]byte, 1))
+		assert.NoError(suite.T(), err)
+		close(reader.gate)
+
+		size, err := io.Copy(ioutil.Discard, part)
+		assert.NoError(suite.T(), err)
+		w.Header().Set("X-Size", strconv.FormatInt(size+1, 10))
+	}))
+	defer srv.Close()
+
+	resp, err := New().Post(srv.URL).FileReader("file", "large.bin", reader, "").Do()
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), strconv.FormatInt(reader.size, 10), resp.Header.Get("X-Size"))
+	}
+}
+
+func (suite *RequestSuite) Test_Multipart_NotSent() {
+	abort := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("aborted")
+		}
+	}
+
+	// The goroutine writing the body is not started for a request
+	// a middleware aborts, nothing would ever read the body.
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		_, err := New().Post(suite.server.URL+"/upload").Field("username", "helloshaohua").Use(abort).Do()
+		assert.EqualError(suite.T(), err, "aborted")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before+5 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(suite.T(), runtime.NumGoroutine(), before+5)
+}
+
+func TestMultipartBody_Write(t *testing.T) {
+	body := newMultipartBody()
+	body.boundary = "boundary"
+	body.parts = append(body.parts, &multipartPart{
+		header: formDataHeader("username", "", ""),
+		open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("helloshaohua")), nil
+		},
+		replayable: true,
+	})
+	assert.True(t, body.replayable())
+
+	// Every reader writes the whole body again.
+	for i := 0; i < 2; i++ {
+		data, err := ioutil.ReadAll(body.reader())
+		assert.NoError(t, err)
+
+		reader := multipart.NewReader(bytes.NewReader(data), "boundary")
+		part, err := reader.NextPart()
+		assert.NoError(t, err)
+		assert.Equal(t, "username", part.FormName())
+	}
+
+	body.parts = append(body.parts, &multipartPart{})
+	assert.False(t, body.replayable())
+}
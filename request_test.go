@@ -0,0 +1,295 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRequestSuite(t *testing.T) {
+	suite.Run(t, new(RequestSuite))
+}
+
+type RequestSuite struct {
+	echoSuite
+}
+
+func (suite *RequestSuite) Test_Concurrent() {
+	cli := New(WithBaseURL(suite.server.URL))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprintf("%d", i)
+			if i%2 == 0 {
+				echo := suite.echo(cli.Get("/echo").QueryParams(Params{{Key: "id", Value: id}}).Do())
+				assert.Equal(suite.T(), http.MethodGet, echo.Method)
+				assert.Equal(suite.T(), "id="+id, echo.Query)
+				assert.Empty(suite.T(), echo.Body)
+				return
+			}
+
+			echo := suite.echo(cli.Post("/echo").Body(strings.NewReader(id)).Do())
+			assert.Equal(suite.T(), http.MethodPost, echo.Method)
+			assert.Empty(suite.T(), echo.Query)
+			assert.Equal(suite.T(), id, echo.Body)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (suite *RequestSuite) Test_Do_Parallel() {
+	// The handler only answers once both requests are in flight,
+	// so it times out if Do serializes the round-trips.
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		arrived.Done()
+		arrived.Wait()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	req := New(WithTimeout(2 * time.Second)).Get(srv.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := req.Do()
+			if assert.NoError(suite.T(), err) {
+				assert.Equal(suite.T(), http.StatusNoContent, resp.StatusCode)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (suite *RequestSuite) Test_NewRequest() {
+	cli := New()
+
+	first, second := cli.Get("/first"), cli.Get("/second")
+	assert.NotSame(suite.T(), first, second)
+	assert.Equal(suite.T(), "/first", first.url)
+	assert.Equal(suite.T(), "/second", second.url)
+}
+
+func (suite *RequestSuite) Test_InsecureSkipVerify() {
+	cli := New()
+	transport := cli.client.Transport
+
+	req := cli.Get(suite.server.URL + "/echo").InsecureSkipVerify(true)
+	assert.True(suite.T(), req.useClient().Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+
+	_, err := req.Do()
+	assert.NoError(suite.T(), err)
+	assert.Same(suite.T(), transport, cli.client.Transport)
+	assert.Same(suite.T(), cli.client, cli.Get("/").useClient())
+}
+
+func (suite *RequestSuite) Test_InsecureSkipVerify_Transport() {
+	// A custom transport can not be copied, so it is never bypassed.
+	var calls int32
+	cli := New(WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return http.DefaultTransport.RoundTrip(req)
+	})))
+
+	_, err := cli.Get(suite.server.URL + "/echo").InsecureSkipVerify(true).Do()
+	assert.Equal(suite.T(), errInsecureTransport, err)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&calls))
+
+	suite.echo(cli.Get(suite.server.URL + "/echo").InsecureSkipVerify(false).Do())
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&calls))
+}
+
+func (suite *RequestSuite) Test_InsecureSkipVerify_Connections() {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secure"))
+	}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The requests share the insecure transport of the client,
+	// so they reuse a single connection.
+	cli := New()
+	for i := 0; i < 20; i++ {
+		resp, err := cli.Get(srv.URL).InsecureSkipVerify(true).Do()
+		if assert.NoError(suite.T(), err) {
+			assert.Equal(suite.T(), "secure", suite.text(resp))
+		}
+	}
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&conns))
+}
+
+func (suite *RequestSuite) Test_Header() {
+	cli := New(
+		WithBaseURL(suite.server.URL),
+		WithHeader("X-Default", "client"),
+		WithHeader("X-Override", "client"),
+		WithHeader("X-Multi", "client"),
+		WithHeader("X-Deleted", "client"),
+	)
+
+	echo := suite.echo(cli.Get("/echo").
+		Header("X-Override", "request").
+		Headers(map[string]string{"Content-Type": "application/json", "authorization": "Bearer token"}).
+		AddHeader("X-Multi", "request").
+		DelHeader("X-Deleted").
+		Do())
+
+	assert.Equal(suite.T(), []string{"client"}, echo.Header["X-Default"])
+	assert.Equal(suite.T(), []string{"request"}, echo.Header["X-Override"])
+	assert.Equal(suite.T(), []string{"client", "request"}, echo.Header["X-Multi"])
+	assert.Equal(suite.T(), []string{"application/json"}, echo.Header["Content-Type"])
+	assert.Equal(suite.T(), []string{"Bearer token"}, echo.Header["Authorization"])
+	assert.NotContains(suite.T(), echo.Header, "X-Deleted")
+
+	// Headers of a request never leak into the client defaults.
+	echo = suite.echo(cli.Get("/echo").Do())
+	assert.Equal(suite.T(), []string{"client"}, echo.Header["X-Override"])
+	assert.Equal(suite.T(), []string{"client"}, echo.Header["X-Deleted"])
+}
+
+func (suite *RequestSuite) Test_UserAgent() {
+	echo := suite.echo(New().Get(suite.server.URL + "/echo").Do())
+	assert.Equal(suite.T(), []string{DefaultUserAgent}, echo.Header["User-Agent"])
+
+	echo = suite.echo(New(WithUserAgent("client/1.0")).Get(suite.server.URL + "/echo").Do())
+	assert.Equal(suite.T(), []string{"client/1.0"}, echo.Header["User-Agent"])
+
+	echo = suite.echo(New(WithUserAgent("client/1.0")).Get(suite.server.URL + "/echo").UserAgent("request/1.0").Do())
+	assert.Equal(suite.T(), []string{"request/1.0"}, echo.Header["User-Agent"])
+}
+
+// blockingServer returns a server whose handler blocks until the client
+// goes away, arrived receives a value for every request and closed for
+// every connection closed by the client.
+func blockingServer() (srv *httptest.Server, arrived, closed chan struct{}) {
+	arrived, closed = make(chan struct{}, 1), make(chan struct{}, 1)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		arrived <- struct{}{}
+		<-req.Context().Done()
+		closed <- struct{}{}
+	}))
+	return srv, arrived, closed
+}
+
+func (suite *RequestSuite) Test_DoContext_Cancel() {
+	srv, arrived, closed := blockingServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-arrived
+		cancel()
+	}()
+
+	resp, err := New().Get(srv.URL).Timeout(time.Minute).DoContext(ctx)
+	assert.Nil(suite.T(), resp)
+	assert.True(suite.T(), errors.Is(err, context.Canceled))
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		suite.T().Fatal("the connection is not closed after cancellation")
+	}
+}
+
+func (suite *RequestSuite) Test_DoContext_Timeout() {
+	srv, _, closed := blockingServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	resp, err := New().Get(srv.URL).Timeout(50 * time.Millisecond).DoContext(ctx)
+	assert.Nil(suite.T(), resp)
+	assert.True(suite.T(), errors.Is(err, context.DeadlineExceeded))
+	<-closed
+}
+
+func (suite *RequestSuite) Test_WithContext() {
+	srv, arrived, closed := blockingServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-arrived
+		cancel()
+	}()
+
+	resp, err := New(WithTimeout(time.Minute)).Get(srv.URL).WithContext(ctx).Do()
+	assert.Nil(suite.T(), resp)
+	assert.True(suite.T(), errors.Is(err, context.Canceled))
+	<-closed
+}
+
+func (suite *RequestSuite) Test_Timeout_SlowBody() {
+	resp, err := New(WithBaseURL(suite.server.URL)).
+		Get("/stream").
+		QueryParams(Params{{Key: "chunks", Value: "5"}, {Key: "interval", Value: "40"}}).
+		Timeout(time.Second).
+		Do()
+	assert.NoError(suite.T(), err)
+
+	// The body is still streamed after Do returns.
+	data, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "0\n1\n2\n3\n4\n", string(data))
+
+	// Closing the body releases the timeout.
+	assert.NoError(suite.T(), resp.Body.Close())
+	assert.True(suite.T(), errors.Is(resp.Request.Context().Err(), context.Canceled))
+}
+
+func (suite *RequestSuite) Test_Timeout_SlowBody_Deadline() {
+	resp, err := New(WithBaseURL(suite.server.URL)).
+		Get("/stream").
+		QueryParams(Params{{Key: "chunks", Value: "50"}, {Key: "interval", Value: "20"}}).
+		Timeout(200 * time.Millisecond).
+		Do()
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	// The timeout covers the whole exchange, including the body.
+	_, err = ioutil.ReadAll(resp.Body)
+	assert.True(suite.T(), errors.Is(err, context.DeadlineExceeded))
+}
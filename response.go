@@ -0,0 +1,94 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Response wraps the http.Response of an executed request.
+//
+// The body is read once by Bytes, String, JSON or XML, which close it
+// and cache its content for later calls. Reading Body directly is still
+// possible, but then it must be closed by the caller.
+type Response struct {
+	*http.Response
+
+	duration time.Duration
+	body     []byte
+	err      error
+	once     sync.Once
+}
+
+func newResponse(resp *http.Response, duration time.Duration) *Response {
+	return &Response{
+		Response: resp,
+		duration: duration,
+	}
+}
+
+// Duration returns the time taken from sending the request
+// until the response headers were received.
+func (resp *Response) Duration() time.Duration {
+	return resp.duration
+}
+
+// Bytes returns the body, reading and closing it on the first call.
+func (resp *Response) Bytes() ([]byte, error) {
+	resp.once.Do(func() {
+		defer resp.Body.Close()
+		resp.body, resp.err = ioutil.ReadAll(resp.Body)
+	})
+	return resp.body, resp.err
+}
+
+// String returns the body as string, see Bytes.
+func (resp *Response) String() (string, error) {
+	body, err := resp.Bytes()
+	return string(body), err
+}
+
+// JSON decode the JSON body into v, see Bytes.
+func (resp *Response) JSON(v interface{}) error {
+	body, err := resp.Bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// XML decode the XML body into v, see Bytes.
+func (resp *Response) XML(v interface{}) error {
+	body, err := resp.Bytes()
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(body, v)
+}
+
+// IsSuccess reports whether the status code is 2xx.
+func (resp *Response) IsSuccess() bool {
+	return resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices
+}
+
+// IsError reports whether the status code is 4xx or 5xx.
+func (resp *Response) IsError() bool {
+	return resp.StatusCode >= http.StatusBadRequest
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closeRecorder struct {
+	*strings.Reader
+	reads  int
+	closed bool
+}
+
+func (body *closeRecorder) Read(p []byte) (int, error) {
+	body.reads++
+	return body.Reader.Read(p)
+}
+
+func (body *closeRecorder) Close() error {
+	body.closed = true
+	return nil
+}
+
+func TestResponse_Bytes(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("hello")}
+	resp := newResponse(&http.Response{StatusCode: http.StatusOK, Body: body}, 0)
+
+	actual, err := resp.String()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", actual)
+	assert.True(t, body.closed)
+
+	// The body is cached after the first read.
+	reads := body.reads
+	data, err := resp.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+	assert.Equal(t, reads, body.reads)
+}
+
+type failingBody struct{}
+
+func (failingBody) Read(p []byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestResponse_Bytes_Error(t *testing.T) {
+	resp := newResponse(&http.Response{Body: ioutil.NopCloser(failingBody{})}, 0)
+
+	_, err := resp.Bytes()
+	assert.EqualError(t, err, "connection reset")
+
+	var v map[string]interface{}
+	assert.EqualError(t, resp.JSON(&v), "connection reset")
+}
+
+func TestResponse_JSON(t *testing.T) {
+	resp := newResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"username":"helloshaohua"}`))}, 0)
+
+	var v struct {
+		Username string `json:"username"`
+	}
+	assert.NoError(t, resp.JSON(&v))
+	assert.Equal(t, "helloshaohua", v.Username)
+}
+
+func TestResponse_XML(t *testing.T) {
+	resp := newResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader(`<user><username>helloshaohua</username></user>`))}, 0)
+
+	var v struct {
+		Username string `xml:"username"`
+	}
+	assert.NoError(t, resp.XML(&v))
+	assert.Equal(t, "helloshaohua", v.Username)
+}
+
+func TestResponse_Status(t *testing.T) {
+	grids := []struct {
+		status  int
+		success bool
+		error   bool
+	}{
+		{status: http.StatusOK, success: true},
+		{status: http.StatusNoContent, success: true},
+		{status: http.StatusFound},
+		{status: http.StatusNotFound, error: true},
+		{status: http.StatusServiceUnavailable, error: true},
+	}
+
+	for _, grid := range grids {
+		resp := newResponse(&http.Response{StatusCode: grid.status, Body: http.NoBody}, 0)
+		assert.Equal(t, grid.success, resp.IsSuccess(), grid.status)
+		assert.Equal(t, grid.error, resp.IsError(), grid.status)
+	}
+}
+
+func (suite *RequestSuite) Test_Response() {
+	resp, err := New().Get(suite.server.URL + "/users").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.True(suite.T(), resp.IsSuccess())
+	assert.True(suite.T(), resp.Duration() > 0)
+
+	var users []map[string]string
+	assert.NoError(suite.T(), resp.JSON(&users))
+	assert.NotEmpty(suite.T(), users)
+
+	resp, err = New().Get(suite.server.URL + "/missing").Do()
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), resp.IsError())
+}
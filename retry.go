@@ -0,0 +1,255 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy configures how a failed request is retried.
+//
+// A request is retried when it fails with an error accepted by
+// RetryOnError, or its response status is one of StatusCodes. The delay
+// before each retry is chosen by exponential backoff with full jitter,
+// unless the response has a Retry-After header, which is honoured up to
+// MaxDelay if set: a response asking to wait longer is returned, not retried.
+// A request whose body can not be rewound by GetBody is not retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the
+	// first one. A value less than 2 disables retrying.
+	MaxAttempts int
+
+	// BaseDelay is the delay ceiling of the first retry, doubled for
+	// every next retry up to MaxDelay, which also bounds Retry-After.
+	// A MaxDelay of 0 leaves both unbounded.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// StatusCodes are the retryable response status codes.
+	StatusCodes []int
+
+	// RetryOnError reports whether a request failing with err is
+	// retried, whatever its method. If nil, an idempotent request is
+	// retried on the errors accepted by DefaultRetryOnError, and another
+	// one only if it failed to connect, so it never reached the server.
+	RetryOnError func(err error) bool
+
+	// OnRetry is called before waiting delay to retry the failed
+	// attempt, resp is nil if the attempt failed with err.
+	OnRetry func(attempt int, resp *http.Response, err error, delay time.Duration)
+}
+
+// DefaultRetryPolicy returns a policy of 3 attempts, retrying on errors
+// and on the 429, 502, 503 and 504 statuses.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		StatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// DefaultRetryOnError retries every error, except the cancellation or
+// expiration of the request context. By default, it only applies to
+// idempotent requests, see RetryPolicy.RetryOnError.
+func DefaultRetryOnError(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// WithRetry set the default retry policy of every request,
+// a request can override it by calling Retry.
+func WithRetry(policy *RetryPolicy) Option {
+	return func(ops *options) {
+		ops.retry = policy
+	}
+}
+
+// Retry set the retry policy of the request, overriding the client one.
+// Use a policy with MaxAttempts 1 to disable retrying.
+func (r *Request) Retry(policy *RetryPolicy) *Request {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.retry = policy
+	return r
+}
+
+func (r *Request) useRetry() *RetryPolicy {
+	if r.retry != nil {
+		return r.retry
+	}
+	return r.owner.options.retry
+}
+
+// attemptFunc execute the attempt number attempt of req.
+type attemptFunc func(req *http.Request, attempt int) (*http.Response, error)
+
+// do execute req by send, retrying it by the policy.
+func (policy *RetryPolicy) do(send attemptFunc, req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := send(req, attempt)
+		if attempt >= policy.MaxAttempts || !policy.retryable(req, resp, err) || !rewindable(req) {
+			return resp, err
+		}
+
+		delay, ok := policy.delay(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, resp, err, delay)
+		}
+
+		if resp != nil {
+			drainBody(resp)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// drainBody reads the start of the body of the discarded resp and closes
+// it, so its connection can be reused.
+func drainBody(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+}
+
+func (policy *RetryPolicy) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if policy.RetryOnError != nil {
+			return policy.RetryOnError(err)
+		}
+		return DefaultRetryOnError(err) && (idempotent(req) || !sent(err))
+	}
+
+	for _, code := range policy.StatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotent reports whether sending req twice has the effect of sending
+// it once, by its method or its idempotency key, like http.Transport.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := req.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = req.Header["X-Idempotency-Key"]
+	}
+	return ok
+}
+
+// sent reports whether the request failing with err may have reached
+// the server, which it did not if the connection failed.
+func sent(err error) bool {
+	var opErr *net.OpError
+	return !errors.As(err, &opErr) || opErr.Op != "dial"
+}
+
+// delay returns the delay before retrying the attempt, or false if
+// the Retry-After header of resp exceeds MaxDelay, when it is set.
+func (policy *RetryPolicy) delay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return delay, policy.MaxDelay <= 0 || delay <= policy.MaxDelay
+		}
+	}
+
+	ceiling := policy.MaxDelay
+	if shift := uint(attempt - 1); shift < 32 {
+		if backoff := policy.BaseDelay << shift; backoff > 0 && (ceiling <= 0 || backoff < ceiling) {
+			ceiling = backoff
+		}
+	}
+	if ceiling <= 0 {
+		return 0, true
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))), true
+}
+
+// retryAfter parse the Retry-After header value, in seconds
+// or HTTP-date form, into the delay from now.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// rewindable reports whether the body of req can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of the rewindable req to send it again,
+// with a new body from GetBody.
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+	return next, nil
+}
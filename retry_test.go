@@ -0,0 +1,296 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 10, 18, 8, 0, 0, 0, time.UTC)
+
+	grids := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{value: "", ok: false},
+		{value: "120", expected: 2 * time.Minute, ok: true},
+		{value: " 0 ", expected: 0, ok: true},
+		{value: "-1", ok: false},
+		{value: "Mon, 18 Oct 2021 08:00:30 GMT", expected: 30 * time.Second, ok: true},
+		{value: "Mon, 18 Oct 2021 07:59:30 GMT", expected: 0, ok: true},
+		{value: "tomorrow", ok: false},
+	}
+
+	for _, grid := range grids {
+		actual, ok := retryAfter(grid.value, now)
+		assert.Equal(t, grid.ok, ok, grid.value)
+		assert.Equal(t, grid.expected, actual, grid.value)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	grids := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{attempt: 1, ceiling: 10 * time.Millisecond},
+		{attempt: 2, ceiling: 20 * time.Millisecond},
+		{attempt: 3, ceiling: 40 * time.Millisecond},
+		{attempt: 4, ceiling: 50 * time.Millisecond},
+		{attempt: 100, ceiling: 50 * time.Millisecond},
+	}
+
+	for _, grid := range grids {
+		for i := 0; i < 100; i++ {
+			delay, ok := policy.delay(grid.attempt, nil)
+			assert.True(t, ok)
+			assert.True(t, delay >= 0 && delay < grid.ceiling, "attempt %d: %s", grid.attempt, delay)
+		}
+	}
+
+	// Retry-After is honoured up to MaxDelay.
+	policy.MaxDelay = 5 * time.Second
+	delay, ok := policy.delay(1, &http.Response{Header: http.Header{"Retry-After": {"3"}}})
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+
+	_, ok = policy.delay(1, &http.Response{Header: http.Header{"Retry-After": {"86400"}}})
+	assert.False(t, ok)
+
+	// Without MaxDelay, Retry-After is unbounded.
+	policy.MaxDelay = 0
+	delay, ok = policy.delay(1, &http.Response{Header: http.Header{"Retry-After": {"86400"}}})
+	assert.True(t, ok)
+	assert.Equal(t, 24*time.Hour, delay)
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	get := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	assert.True(t, policy.retryable(get, nil, errors.New("connection reset by peer")))
+	assert.False(t, policy.retryable(get, nil, &url.Error{Op: "Get", URL: "/", Err: context.Canceled}))
+	assert.False(t, policy.retryable(get, nil, context.DeadlineExceeded))
+	assert.True(t, policy.retryable(get, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.False(t, policy.retryable(get, &http.Response{StatusCode: http.StatusInternalServerError}, nil))
+	assert.False(t, policy.retryable(get, &http.Response{StatusCode: http.StatusOK}, nil))
+
+	policy.RetryOnError = func(err error) bool { return false }
+	assert.False(t, policy.retryable(get, nil, errors.New("connection reset by peer")))
+}
+
+func TestRetryPolicy_Retryable_Idempotent(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	reset := &url.Error{Op: "Post", URL: "/", Err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}}
+	refused := &url.Error{Op: "Post", URL: "/", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+
+	grids := []struct {
+		method   string
+		header   string
+		err      error
+		expected bool
+	}{
+		{method: http.MethodGet, err: reset, expected: true},
+		{method: http.MethodHead, err: reset, expected: true},
+		{method: http.MethodOptions, err: reset, expected: true},
+		{method: http.MethodPut, err: reset, expected: true},
+		{method: http.MethodDelete, err: reset, expected: true},
+		{method: http.MethodPost, err: reset, expected: false},
+		{method: http.MethodPatch, err: reset, expected: false},
+		{method: http.MethodPost, header: "Idempotency-Key", err: reset, expected: true},
+		{method: http.MethodPost, err: refused, expected: true},
+		{method: http.MethodPatch, err: refused, expected: true},
+	}
+
+	for _, grid := range grids {
+		req := httptest.NewRequest(grid.method, "/", nil)
+		if grid.header != "" {
+			req.Header.Set(grid.header, "1")
+		}
+		assert.Equal(t, grid.expected, policy.retryable(req, nil, grid.err), "%s %s", grid.method, grid.err)
+	}
+
+	// RetryOnError opts into retrying any method.
+	policy.RetryOnError = DefaultRetryOnError
+	assert.True(t, policy.retryable(httptest.NewRequest(http.MethodPost, "/", nil), nil, reset))
+}
+
+func testRetryPolicy(attempts int, retries *int32) *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = attempts
+	policy.BaseDelay = time.Millisecond
+	policy.OnRetry = func(attempt int, resp *http.Response, err error, delay time.Duration) {
+		atomic.AddInt32(retries, 1)
+	}
+	return policy
+}
+
+func (suite *RequestSuite) flakyURL(query string) string {
+	return suite.server.URL + "/flaky?key=" + url.QueryEscape(suite.T().Name()) + "&" + query
+}
+
+func (suite *RequestSuite) Test_Retry_Status() {
+	var retries int32
+	resp, err := New().Get(suite.flakyURL("fail=2")).Retry(testRetryPolicy(3, &retries)).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "3", resp.Header.Get("X-Attempt"))
+	assert.Equal(suite.T(), int32(2), retries)
+}
+
+func (suite *RequestSuite) Test_Retry_Exhausted() {
+	var retries int32
+	resp, err := New().Get(suite.flakyURL("fail=5&code=502")).Retry(testRetryPolicy(2, &retries)).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(suite.T(), "2", resp.Header.Get("X-Attempt"))
+	assert.Equal(suite.T(), int32(1), retries)
+}
+
+func (suite *RequestSuite) Test_Retry_Error() {
+	var retries int32
+	resp, err := New().Get(suite.flakyURL("fail=1&reset")).Retry(testRetryPolicy(3, &retries)).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "2", resp.Header.Get("X-Attempt"))
+	assert.Equal(suite.T(), int32(1), retries)
+}
+
+func (suite *RequestSuite) Test_Retry_Error_NotIdempotent() {
+	// A POST may have been processed before the connection was reset.
+	var retries int32
+	_, err := New().Post(suite.flakyURL("fail=1&reset")).JSON(map[string]int{"id": 1}).Retry(testRetryPolicy(3, &retries)).Do()
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), int32(0), retries)
+
+	// A POST which failed to connect never reached the server.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(suite.T(), err)
+	listener.Close()
+
+	_, err = New().Post("http://" + listener.Addr().String()).JSON(map[string]int{"id": 1}).Retry(testRetryPolicy(3, &retries)).Do()
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), int32(2), retries)
+}
+
+func (suite *RequestSuite) Test_Retry_Body() {
+	var retries int32
+	echo := suite.echo(New().Post(suite.flakyURL("fail=2")).
+		JSON(map[string]int{"id": 1}).
+		Retry(testRetryPolicy(3, &retries)).
+		Do())
+	assert.Equal(suite.T(), `{"id":1}`, echo.Body)
+	assert.Equal(suite.T(), int32(2), retries)
+}
+
+func (suite *RequestSuite) Test_Retry_NotRewindable() {
+	var retries int32
+	body := struct{ *strings.Reader }{strings.NewReader(`{"id":1}`)}
+
+	resp, err := New().Post(suite.flakyURL("fail=1")).Body(body).Retry(testRetryPolicy(3, &retries)).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(suite.T(), int32(0), retries)
+}
+
+func (suite *RequestSuite) Test_Retry_RetryAfter() {
+	var delays []time.Duration
+	policy := testRetryPolicy(2, new(int32))
+	policy.OnRetry = func(attempt int, resp *http.Response, err error, delay time.Duration) {
+		delays = append(delays, delay)
+	}
+
+	resp, err := New().Get(suite.flakyURL("fail=1&code=429&retry_after=1")).Retry(policy).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), []time.Duration{time.Second}, delays)
+}
+
+func (suite *RequestSuite) Test_Retry_RetryAfter_MaxDelay() {
+	// A response asking to wait longer than MaxDelay is returned.
+	var retries int32
+	start := time.Now()
+	resp, err := New().Get(suite.flakyURL("fail=1&code=429&retry_after=86400")).Retry(testRetryPolicy(2, &retries)).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(suite.T(), "86400", resp.Header.Get("Retry-After"))
+	assert.Equal(suite.T(), int32(0), retries)
+	assert.True(suite.T(), time.Since(start) < time.Second)
+}
+
+func (suite *RequestSuite) Test_Retry_RetryAfter_NoMaxDelay() {
+	// Without MaxDelay, Retry-After is always honoured.
+	var retries int32
+	policy := &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   10 * time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+		OnRetry: func(attempt int, resp *http.Response, err error, delay time.Duration) {
+			atomic.AddInt32(&retries, 1)
+		},
+	}
+
+	resp, err := New().Get(suite.flakyURL("fail=1&retry_after=1")).Retry(policy).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), int32(1), retries)
+}
+
+func (suite *RequestSuite) Test_Retry_Timeout() {
+	// The timeout also stops waiting for the next attempt.
+	policy := testRetryPolicy(2, new(int32))
+	policy.MaxDelay = time.Minute
+
+	start := time.Now()
+	resp, err := New().Get(suite.flakyURL("fail=1&retry_after=10")).
+		Retry(policy).
+		Timeout(100 * time.Millisecond).
+		Do()
+	assert.Nil(suite.T(), resp)
+	assert.True(suite.T(), errors.Is(err, context.DeadlineExceeded))
+	assert.True(suite.T(), time.Since(start) < 5*time.Second)
+}
+
+func (suite *RequestSuite) Test_Retry_Client() {
+	var retries int32
+	cli := New(WithRetry(testRetryPolicy(3, &retries)))
+
+	resp, err := cli.Get(suite.flakyURL("fail=1")).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), int32(1), retries)
+
+	// The policy of a request overrides the client one,
+	// the key already failed once and succeeded once.
+	resp, err = cli.Get(suite.flakyURL("fail=4")).Retry(&RetryPolicy{MaxAttempts: 1}).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(suite.T(), "3", resp.Header.Get("X-Attempt"))
+	assert.Equal(suite.T(), int32(1), retries)
+}
@@ -0,0 +1,240 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coolstina/httpclient/httpsig"
+	"github.com/coolstina/httpclient/rawquery"
+)
+
+// WithMessageSignature set the default credentials of every request to the
+// HTTP message signatures of signer, RFC 9421. Every attempt is signed
+// again.
+func WithMessageSignature(signer *httpsig.Signer) Option {
+	return func(ops *options) {
+		ops.credentials = messageSignature(signer)
+	}
+}
+
+// MessageSignature set the credentials of the request to the HTTP message
+// signatures of signer, replacing the client ones, see WithMessageSignature.
+func (r *Request) MessageSignature(signer *httpsig.Signer) *Request {
+	return r.setCredentials(messageSignature(signer))
+}
+
+func messageSignature(signer *httpsig.Signer) *credentials {
+	return &credentials{header: "Signature", auth: &messageSigner{signer: signer}}
+}
+
+// messageSigner signs requests by HTTP message signatures.
+type messageSigner struct {
+	signer *httpsig.Signer
+}
+
+// do execute req by send, signed.
+func (auth *messageSigner) do(send Handler, req *http.Request) (*http.Response, error) {
+	signed := req.Clone(req.Context())
+	if err := auth.signer.Sign(signed, time.Now()); err != nil {
+		return nil, err
+	}
+	return send(signed)
+}
+
+// Canonicalizer builds the string signed from the parameters of a request,
+// as many payment gateways require: the parameters sorted by key, then by
+// value, key1=value1&key2=value2.
+type Canonicalizer struct {
+	Exclude   ParamKeys           // The parameters which are not signed.
+	SkipEmpty bool                // Skip the parameters whose value is empty.
+	Escape    func(string) string // Of the keys and values, raw if nil.
+}
+
+// Canonicalize returns the canonical string of params.
+func (c *Canonicalizer) Canonicalize(params Params) string {
+	if c == nil {
+		c = &Canonicalizer{}
+	}
+
+	sorted := make(Params, 0, len(params))
+	for _, param := range params {
+		if c.Exclude.Exists(param.Key) || c.SkipEmpty && param.Value == "" {
+			continue
+		}
+		sorted = append(sorted, param)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+
+	escape := c.Escape
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+
+	var buf strings.Builder
+	for _, param := range sorted {
+		if buf.Len() > 0 {
+			buf.WriteByte('&')
+		}
+		buf.WriteString(escape(param.Key))
+		buf.WriteByte('=')
+		buf.WriteString(escape(param.Value))
+	}
+	return buf.String()
+}
+
+// CanonicalizeRawQuery returns the canonical string of the parameters of
+// the URL encoded rawQuery, e.g. the query or the form body of a request.
+func (c *Canonicalizer) CanonicalizeRawQuery(rawQuery string) string {
+	return c.Canonicalize(parseRawParams(rawQuery))
+}
+
+// parseRawParams parse the URL encoded rawQuery into Params, in order.
+func parseRawParams(rawQuery string) Params {
+	queries := rawquery.ParseRawQuery(rawQuery)
+	params := make(Params, 0, len(queries))
+	for _, query := range queries {
+		key, value := query.Field, query.Value.(string)
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		params = append(params, Param{Key: key, Value: value})
+	}
+	return params
+}
+
+// ParamsSigner signs the parameters of requests, of the query and of a form
+// body, as many payment gateways require: the canonical string of the
+// parameters is signed, and the signature added as another parameter, to
+// the form body if any.
+type ParamsSigner struct {
+	Param         string // Of the signature, sign if empty.
+	Canonicalizer *Canonicalizer
+	Algorithm     httpsig.Algorithm
+	Encode        func([]byte) string // Of the signature, hex if nil.
+}
+
+// WithParamsSignature set the default credentials of every request to the
+// signature of its parameters by signer.
+func WithParamsSignature(signer *ParamsSigner) Option {
+	return func(ops *options) {
+		ops.credentials = &credentials{auth: signer}
+	}
+}
+
+// ParamsSignature set the credentials of the request to the signature of
+// its parameters by signer, replacing the client ones.
+func (r *Request) ParamsSignature(signer *ParamsSigner) *Request {
+	return r.setCredentials(&credentials{auth: signer})
+}
+
+// Signature returns the encoded signature of params,
+// without the signature parameter.
+func (signer *ParamsSigner) Signature(params Params) (string, error) {
+	signature, err := signer.Algorithm.Sign([]byte(signer.Canonicalizer.Canonicalize(params.Remove(signer.param()))))
+	if err != nil {
+		return "", err
+	}
+
+	if signer.Encode != nil {
+		return signer.Encode(signature), nil
+	}
+	return hex.EncodeToString(signature), nil
+}
+
+func (signer *ParamsSigner) param() string {
+	if signer.Param != "" {
+		return signer.Param
+	}
+	return "sign"
+}
+
+// do execute req by send, with its parameters signed.
+func (signer *ParamsSigner) do(send Handler, req *http.Request) (*http.Response, error) {
+	signed, err := signer.sign(req)
+	if err != nil {
+		return nil, err
+	}
+	return send(signed)
+}
+
+func (signer *ParamsSigner) sign(req *http.Request) (*http.Request, error) {
+	params := parseRawParams(req.URL.RawQuery)
+
+	var form []byte
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	isForm := mediaType == "application/x-www-form-urlencoded" && req.Body != nil && req.Body != http.NoBody
+	if isForm {
+		if req.GetBody == nil {
+			return nil, errors.New("httpclient: the form body is not rewindable")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		form, err = ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, parseRawParams(string(form))...)
+	}
+
+	signature, err := signer.Signature(params)
+	if err != nil {
+		return nil, err
+	}
+	param := url.QueryEscape(signer.param()) + "=" + url.QueryEscape(signature)
+
+	signed := req.Clone(req.Context())
+	if !isForm {
+		signed.URL.RawQuery = appendRawParam(signed.URL.RawQuery, param)
+		return signed, nil
+	}
+
+	req.Body.Close()
+	form = []byte(appendRawParam(string(form), param))
+	signed.ContentLength = int64(len(form))
+	signed.Body = ioutil.NopCloser(bytes.NewReader(form))
+	signed.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(form)), nil
+	}
+	return signed, nil
+}
+
+func appendRawParam(rawQuery, param string) string {
+	if rawQuery == "" {
+		return param
+	}
+	return rawQuery + "&" + param
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/coolstina/httpclient/httpsig"
+	"github.com/coolstina/httpclient/test/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalizer_Canonicalize(t *testing.T) {
+	params := Params{
+		{Key: "time", Value: "10"},
+		{Key: "b", Value: "2"},
+		{Key: "a", Value: "x y"},
+		{Key: "b", Value: "1"},
+		{Key: "empty", Value: ""},
+		{Key: "sign_type", Value: "HMAC-SHA256"},
+	}
+
+	grids := []struct {
+		canonicalizer *Canonicalizer
+		expected      string
+	}{
+		{canonicalizer: nil, expected: "a=x y&b=1&b=2&empty=&sign_type=HMAC-SHA256&time=10"},
+		{canonicalizer: &Canonicalizer{SkipEmpty: true}, expected: "a=x y&b=1&b=2&sign_type=HMAC-SHA256&time=10"},
+		{canonicalizer: &Canonicalizer{Exclude: ParamKeys{"sign_type", "time"}}, expected: "a=x y&b=1&b=2&empty="},
+		{canonicalizer: &Canonicalizer{SkipEmpty: true, Escape: url.QueryEscape}, expected: "a=x+y&b=1&b=2&sign_type=HMAC-SHA256&time=10"},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, grid.canonicalizer.Canonicalize(params))
+	}
+}
+
+func TestCanonicalizer_CanonicalizeRawQuery(t *testing.T) {
+	canonicalizer := &Canonicalizer{SkipEmpty: true}
+	assert.Equal(t, "a=x y&b=YWJj==&c=1", canonicalizer.CanonicalizeRawQuery("c=1&b=YWJj%3D%3D&a=x+y&d=&flag"))
+}
+
+func TestParamsSigner_Signature(t *testing.T) {
+	params := Params{
+		{Key: "out_trade_no", Value: "1"},
+		{Key: "amount", Value: "100"},
+		{Key: "nonce", Value: "ibuaiVcKdpRxkhJA"},
+		{Key: "time", Value: "10"},
+		{Key: "body", Value: "test"},
+		{Key: "sign", Value: "ignored"},
+		{Key: "attach", Value: ""},
+	}
+
+	grids := []struct {
+		signer   *ParamsSigner
+		expected string
+	}{
+		{
+			signer: &ParamsSigner{
+				Canonicalizer: &Canonicalizer{SkipEmpty: true},
+				Algorithm:     httpsig.HMACSHA256([]byte("secret")),
+			},
+			expected: "775b1518c36de3d821e3ee9cb09789e2841d6722d61ff0f11e6aedeca3d89cf5",
+		},
+		{
+			signer: &ParamsSigner{
+				Canonicalizer: &Canonicalizer{SkipEmpty: true},
+				Algorithm:     httpsig.HMACSHA256([]byte("secret")),
+				Encode:        base64.StdEncoding.EncodeToString,
+			},
+			expected: "d1sVGMNt49gh4+6csJeJ4oQdZyLWH/DxHmrt7KPYnPU=",
+		},
+	}
+
+	for _, grid := range grids {
+		actual, err := grid.signer.Signature(params)
+		assert.NoError(t, err)
+		assert.Equal(t, grid.expected, actual)
+	}
+}
+
+func (suite *RequestSuite) Test_MessageSignature() {
+	client := New(WithBaseURL(suite.server.URL), WithMessageSignature(&httpsig.Signer{
+		KeyID:      server.SignatureEd25519KeyID,
+		Algorithm:  httpsig.Ed25519(server.SignatureEd25519Key),
+		Components: []string{"@method", "@target-uri", "content-type", "content-digest"},
+	}))
+
+	echo := suite.echo(client.Post("/signature").QueryParams(Params{{Key: "id", Value: "1"}}).JSON(map[string]int{"id": 1}).Do())
+	assert.Equal(suite.T(), `{"id":1}`, echo.Body)
+	assert.True(suite.T(), strings.HasPrefix(echo.Header["Signature-Input"][0], `sig1=("@method" "@target-uri" "content-type" "content-digest");created=`))
+
+	// The request signature replaces the client one.
+	echo = suite.echo(client.Get("/signature").MessageSignature(&httpsig.Signer{
+		Label:      "hmac",
+		KeyID:      server.SignatureHMACKeyID,
+		Algorithm:  httpsig.HMACSHA256([]byte(server.SignatureHMACSecret)),
+		Components: []string{"@method", "@target-uri"},
+	}).Do())
+	assert.True(suite.T(), strings.HasPrefix(echo.Header["Signature"][0], "hmac=:"))
+
+	resp, err := New().Get(suite.server.URL + "/signature").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (suite *RequestSuite) Test_ParamsSignature() {
+	signer := &ParamsSigner{
+		Canonicalizer: &Canonicalizer{SkipEmpty: true},
+		Algorithm:     httpsig.HMACSHA256([]byte("secret")),
+	}
+
+	// The server verifies the signature of the query and form parameters.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		params := append(parseRawParams(r.URL.RawQuery), parseRawParams(string(body))...)
+		expected, err := signer.Signature(params)
+		if err != nil || params.ByName("sign") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		_, _ = w.Write(body)
+	}))
+	defer ts.Close()
+
+	client := New(WithBaseURL(ts.URL), WithParamsSignature(signer))
+
+	resp, err := client.Get("/").QueryParams(Params{{Key: "b", Value: "2"}, {Key: "a", Value: "x y"}}).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Post("/").QueryParams(Params{{Key: "app_id", Value: "1"}}).
+		Form(Params{{Key: "amount", Value: "100"}, {Key: "attach", Value: ""}}).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.True(suite.T(), strings.HasPrefix(suite.text(resp), "amount=100&attach=&sign="))
+
+	_, err = client.Post("/").Header("Content-Type", "application/x-www-form-urlencoded").
+		Body(ioutil.NopCloser(strings.NewReader("amount=100"))).Do()
+	assert.EqualError(suite.T(), err, "httpclient: the form body is not rewindable")
+}
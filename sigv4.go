@@ -0,0 +1,357 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnsignedPayload is the X-Amz-Content-Sha256 header of a request
+// whose body is not signed, see WithSignV4.
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	emptySHA256     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// sigV4Unsigned are the headers which are not signed, since they may
+// be changed on the way to the server.
+var sigV4Unsigned = map[string]bool{
+	"Authorization":   true,
+	"User-Agent":      true,
+	"X-Amzn-Trace-Id": true,
+	"Expect":          true,
+}
+
+// WithSignV4 set the default credentials of every request to the AWS
+// Signature Version 4 of the access key, e.g. for S3 or MinIO. The session
+// token is the one of temporary credentials, empty otherwise.
+//
+// Every attempt is signed again, with the hash of the body if it is
+// rewindable. A body which is not rewindable is sent unsigned, like the
+// one of a request whose X-Amz-Content-Sha256 header is UnsignedPayload,
+// which S3 accepts.
+func WithSignV4(accessKey, secretKey, sessionToken, region, service string) Option {
+	return func(ops *options) {
+		ops.credentials = signV4(accessKey, secretKey, sessionToken, region, service)
+	}
+}
+
+// SignV4 set the credentials of the request to the AWS Signature Version 4
+// of the access key, replacing the client ones, see WithSignV4.
+func (r *Request) SignV4(accessKey, secretKey, sessionToken, region, service string) *Request {
+	return r.setCredentials(signV4(accessKey, secretKey, sessionToken, region, service))
+}
+
+// PresignV4 returns the URL of the request presigned by its AWS Signature
+// Version 4 credentials or the client ones, valid for expires. Only the
+// host is signed, not the other headers nor the body.
+func (r *Request) PresignV4(expires time.Duration) (string, error) {
+	call, err := r.build(context.Background())
+	if err != nil {
+		return "", err
+	}
+	if call.cancel != nil {
+		call.cancel()
+	}
+	if call.req.Body != nil {
+		// Release the unread request body.
+		call.req.Body.Close()
+	}
+
+	signer, ok := call.auth.(*signerV4)
+	if !ok {
+		return "", errors.New("httpclient: no signature version 4 credentials")
+	}
+	return signer.presign(call.req, expires, time.Now())
+}
+
+func signV4(accessKey, secretKey, sessionToken, region, service string) *credentials {
+	return &credentials{
+		header: "Authorization",
+		auth: &signerV4{
+			accessKey:    accessKey,
+			secretKey:    secretKey,
+			sessionToken: sessionToken,
+			region:       region,
+			service:      service,
+		},
+	}
+}
+
+// signerV4 signs requests with AWS Signature Version 4.
+type signerV4 struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	region       string
+	service      string
+}
+
+// do execute req by send, signed.
+func (signer *signerV4) do(send Handler, req *http.Request) (*http.Response, error) {
+	signed, err := signer.sign(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return send(signed)
+}
+
+// sign returns a copy of req signed at now by the Authorization header.
+func (signer *signerV4) sign(req *http.Request, now time.Time) (*http.Request, error) {
+	payload, err := payloadHash(req)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := req.Clone(req.Context())
+	if signer.service == "s3" || payload == UnsignedPayload {
+		signed.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+	signed.Header.Set("X-Amz-Date", now.UTC().Format(sigV4TimeFormat))
+	if signer.sessionToken != "" {
+		signed.Header.Set("X-Amz-Security-Token", signer.sessionToken)
+	}
+
+	headers, canonical := canonicalHeaders(signed)
+	signature := signer.signature(now, signer.canonicalRequest(signed, canonical, headers, payload))
+	signed.Header.Set("Authorization", sigV4Algorithm+
+		" Credential="+signer.accessKey+"/"+signer.scope(now)+
+		", SignedHeaders="+headers+
+		", Signature="+signature)
+	return signed, nil
+}
+
+// presign returns the URL of req signed at now by the query parameters.
+func (signer *signerV4) presign(req *http.Request, expires time.Duration, now time.Time) (string, error) {
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", signer.accessKey+"/"+signer.scope(now))
+	query.Set("X-Amz-Date", now.UTC().Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if signer.sessionToken != "" {
+		query.Set("X-Amz-Security-Token", signer.sessionToken)
+	}
+
+	presigned := *req.URL
+	presigned.RawQuery = canonicalQuery(query)
+	canonical := "host:" + requestHost(req) + "\n"
+	signature := signer.signature(now, signer.canonicalRequest(&http.Request{
+		Method: req.Method,
+		URL:    &presigned,
+	}, canonical, "host", UnsignedPayload))
+
+	presigned.RawQuery += "&X-Amz-Signature=" + signature
+	return presigned.String(), nil
+}
+
+func (signer *signerV4) scope(now time.Time) string {
+	return now.UTC().Format("20060102") + "/" + signer.region + "/" + signer.service + "/aws4_request"
+}
+
+func (signer *signerV4) canonicalRequest(req *http.Request, canonical, headers, payload string) string {
+	return strings.Join([]string{
+		req.Method,
+		signer.canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonical,
+		headers,
+		payload,
+	}, "\n")
+}
+
+// canonicalPath returns the path of u encoded once for S3, whose keys may
+// contain any character. For the other services, the path as sent, already
+// encoded, is normalized and encoded again.
+func (signer *signerV4) canonicalPath(u *url.URL) string {
+	if signer.service == "s3" {
+		if u.Path == "" {
+			return "/"
+		}
+		// Encode the segments, keeping an encoded slash of a key.
+		segments := strings.Split(u.EscapedPath(), "/")
+		for i, segment := range segments {
+			if unescaped, err := url.PathUnescape(segment); err == nil {
+				segment = unescaped
+			}
+			segments[i] = sigV4Escape(segment, true)
+		}
+		return strings.Join(segments, "/")
+	}
+
+	p := u.EscapedPath()
+	if p != "" {
+		cleaned := path.Clean(p)
+		if strings.HasSuffix(p, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
+		p = cleaned
+	}
+	if p == "" {
+		p = "/"
+	}
+	return sigV4Escape(p, false)
+}
+
+func (signer *signerV4) signature(now time.Time, canonicalRequest string) string {
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.UTC().Format(sigV4TimeFormat),
+		signer.scope(now),
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+signer.secretKey), now.UTC().Format("20060102"))
+	key = hmacSHA256(key, signer.region)
+	key = hmacSHA256(key, signer.service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// payloadHash returns the X-Amz-Content-Sha256 header of req if set,
+// or the hex SHA-256 of its body, or UnsignedPayload if the body is
+// not rewindable.
+func payloadHash(req *http.Request) (string, error) {
+	if payload := req.Header.Get("X-Amz-Content-Sha256"); payload != "" {
+		return payload, nil
+	}
+
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+		return emptySHA256, nil
+	case req.GetBody == nil:
+		return UnsignedPayload, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	digest := sha256.New()
+	if _, err := io.Copy(digest, body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// canonicalHeaders returns the names of the signed headers of req, and
+// the canonical headers, with their values trimmed.
+func canonicalHeaders(req *http.Request) (headers, canonical string) {
+	values := map[string]string{"host": requestHost(req)}
+	names := []string{"host"}
+	for key, vals := range req.Header {
+		if sigV4Unsigned[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+
+		trimmed := make([]string, len(vals))
+		for i, val := range vals {
+			trimmed[i] = strings.Join(strings.Fields(val), " ")
+		}
+		name := strings.ToLower(key)
+		values[name] = strings.Join(trimmed, ",")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte(':')
+		buf.WriteString(values[name])
+		buf.WriteByte('\n')
+	}
+	return strings.Join(names, ";"), buf.String()
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// canonicalQuery returns the query encoded as Params sorted by key,
+// then by value.
+func canonicalQuery(query url.Values) string {
+	params := make(Params, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			params = append(params, Param{Key: sigV4Escape(key, true), Value: sigV4Escape(value, true)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].Key != params[j].Key {
+			return params[i].Key < params[j].Key
+		}
+		return params[i].Value < params[j].Value
+	})
+
+	var buf strings.Builder
+	for _, param := range params {
+		if buf.Len() > 0 {
+			buf.WriteByte('&')
+		}
+		buf.WriteString(param.Key)
+		buf.WriteByte('=')
+		buf.WriteString(param.Value)
+	}
+	return buf.String()
+}
+
+// sigV4Escape percent-encodes s except the unreserved characters of
+// RFC 3986, and the slashes unless encodeSlash is true.
+func sigV4Escape(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/' && !encodeSlash:
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte('%')
+			buf.WriteByte(hexDigits[c>>4])
+			buf.WriteByte(hexDigits[c&15])
+		}
+	}
+	return buf.String()
+}
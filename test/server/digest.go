@@ -0,0 +1,203 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The credentials accepted by Digest.
+const (
+	DigestUsername = "kitty"
+	DigestPassword = "secret"
+	DigestRealm    = "httpclient@test"
+	digestOpaque   = "5ccc069c403ebaf9f0171e9517f40e41"
+)
+
+var digest = struct {
+	sync.Mutex
+	nonces map[string]int64 // The last nonce count of every issued nonce.
+}{nonces: make(map[string]int64)}
+
+// Digest echoes the requests authenticated by the digest access
+// authentication of RFC 7616, and challenges the others. The query
+// parameter algorithm (default MD5) is the algorithm of the challenge,
+// qop (default auth) its quality of protection, and if userhash is set,
+// the username must be hashed.
+//
+// The nonce count of a nonce must increase with every request, the
+// X-Digest-Nc response header is the nonce count of the request.
+func Digest(ctx *gin.Context) {
+	algorithm := ctx.DefaultQuery("algorithm", "MD5")
+	qop := ctx.DefaultQuery("qop", "auth")
+	_, userhash := ctx.GetQuery("userhash")
+
+	newHash := digestHash(algorithm)
+	if newHash == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": "unsupported algorithm"})
+		return
+	}
+
+	body, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+	ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	params := parseDigest(ctx.GetHeader("Authorization"))
+	if params != nil && digestValid(ctx.Request, params, algorithm, qop, userhash, body) {
+		ctx.Header("X-Digest-Nc", params["nc"])
+		Echo(ctx)
+		return
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	digest.Lock()
+	digest.nonces[hex.EncodeToString(nonce)] = 0
+	digest.Unlock()
+
+	challenge := fmt.Sprintf(`Digest realm="%s", qop="%s", algorithm=%s, nonce="%x", opaque="%s"`,
+		DigestRealm, qop, algorithm, nonce, digestOpaque)
+	if userhash {
+		challenge += ", userhash=true"
+	}
+	ctx.Header("WWW-Authenticate", challenge)
+	ctx.JSON(http.StatusUnauthorized, gin.H{"result": "unauthorized"})
+}
+
+func digestValid(req *http.Request, params map[string]string, algorithm, qop string, userhash bool, body []byte) bool {
+	newHash := digestHash(algorithm)
+	hexHash := func(s string) string {
+		h := newHash()
+		io.WriteString(h, s)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	username := DigestUsername
+	if userhash {
+		username = hexHash(DigestUsername + ":" + DigestRealm)
+	}
+
+	offered := false
+	for _, item := range strings.Split(qop, ",") {
+		offered = offered || strings.TrimSpace(item) == params["qop"]
+	}
+
+	if params["username"] != username || params["realm"] != DigestRealm || params["opaque"] != digestOpaque ||
+		!strings.EqualFold(params["algorithm"], algorithm) || params["uri"] != req.URL.RequestURI() || !offered {
+		return false
+	}
+
+	nc, err := strconv.ParseInt(params["nc"], 16, 64)
+	if err != nil {
+		return false
+	}
+	digest.Lock()
+	last, ok := digest.nonces[params["nonce"]]
+	if ok && nc > last {
+		digest.nonces[params["nonce"]] = nc
+	}
+	digest.Unlock()
+	if !ok || nc <= last {
+		return false
+	}
+
+	a1 := DigestUsername + ":" + DigestRealm + ":" + DigestPassword
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		a1 = hexHash(a1) + ":" + params["nonce"] + ":" + params["cnonce"]
+	}
+	a2 := req.Method + ":" + params["uri"]
+	if params["qop"] == "auth-int" {
+		a2 += ":" + hexHash(string(body))
+	}
+
+	expected := hexHash(strings.Join([]string{
+		hexHash(a1), params["nonce"], params["nc"], params["cnonce"], params["qop"], hexHash(a2),
+	}, ":"))
+	return params["response"] == expected
+}
+
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	}
+	return nil
+}
+
+// parseDigest parses the Digest credentials of the Authorization header,
+// or returns nil.
+func parseDigest(header string) map[string]string {
+	const scheme = "Digest "
+	if !strings.HasPrefix(header, scheme) {
+		return nil
+	}
+
+	params := make(map[string]string)
+	s := header[len(scheme):]
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return nil
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
+
+		if strings.HasPrefix(s, `"`) {
+			var value strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+				if i < len(s) {
+					value.WriteByte(s[i])
+				}
+			}
+			if i >= len(s) {
+				return nil
+			}
+			params[key] = value.String()
+			s = s[i+1:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			params[key] = s[:end]
+			s = s[end:]
+		}
+		s = strings.TrimLeft(s, ", ")
+	}
+	return params
+}
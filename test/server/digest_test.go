@@ -0,0 +1,95 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDigest(t *testing.T) {
+	grids := []struct {
+		header   string
+		expected map[string]string
+	}{
+		{header: `Basic a2l0dHk6c2VjcmV0`},
+		{
+			header:   `Digest username="kitty", nc=00000001, realm="a \"quoted\" realm"`,
+			expected: map[string]string{"username": "kitty", "nc": "00000001", "realm": `a "quoted" realm`},
+		},
+		{header: `Digest username="kitty`},
+		{header: `Digest username`},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, parseDigest(grid.header), grid.header)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	engine := NewEngine()
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/digest", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	challenge := rec.Header().Get("WWW-Authenticate")
+	params := parseDigest(challenge)
+	if !assert.NotNil(t, params) {
+		return
+	}
+	assert.Equal(t, DigestRealm, params["realm"])
+	assert.Equal(t, "auth", params["qop"])
+
+	hexHash := func(s string) string {
+		return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+	}
+	authorization := func(nc string) string {
+		a1 := hexHash(DigestUsername + ":" + DigestRealm + ":" + DigestPassword)
+		a2 := hexHash("GET:/digest")
+		response := hexHash(strings.Join([]string{a1, params["nonce"], nc, "cnonce", "auth", a2}, ":"))
+		return fmt.Sprintf(`Digest username="%s", realm="%s", uri="/digest", algorithm=MD5, nonce="%s", `+
+			`nc=%s, cnonce="cnonce", qop=auth, response="%s", opaque="%s"`,
+			DigestUsername, DigestRealm, params["nonce"], nc, response, params["opaque"])
+	}
+
+	grids := []struct {
+		nc   string
+		code int
+	}{
+		{nc: "00000001", code: http.StatusOK},
+		{nc: "00000001", code: http.StatusUnauthorized},
+		{nc: "00000003", code: http.StatusOK},
+		{nc: "00000002", code: http.StatusUnauthorized},
+	}
+
+	for _, grid := range grids {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/digest", nil)
+		req.Header.Set("Authorization", authorization(grid.nc))
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, grid.code, rec.Code, grid.nc)
+		if grid.code == http.StatusOK {
+			assert.Equal(t, grid.nc, rec.Header().Get("X-Digest-Nc"))
+		}
+	}
+}
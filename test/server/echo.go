@@ -0,0 +1,48 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EchoResponse describes the request received by the Echo handler.
+type EchoResponse struct {
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Query  string              `json:"query"`
+	Header map[string][]string `json:"header"`
+	Body   string              `json:"body"`
+}
+
+// Echo responds with the method, path, query, headers and body of the request.
+func Echo(ctx *gin.Context) {
+	body, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EchoResponse{
+		Method: ctx.Request.Method,
+		Path:   ctx.Request.URL.Path,
+		Query:  ctx.Request.URL.RawQuery,
+		Header: ctx.Request.Header,
+		Body:   string(body),
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var flaky = struct {
+	sync.Mutex
+	attempts map[string]int
+}{attempts: make(map[string]int)}
+
+// Flaky fails the first requests of every query parameter key, then echoes
+// the request like Echo. The query parameter fail (default 1) is the
+// number of failures, code (default 503) their status code, and
+// retry_after their Retry-After header. If reset is set, a failure
+// closes the connection instead of responding.
+//
+// The X-Attempt response header is the attempt number of the key.
+func Flaky(ctx *gin.Context) {
+	fail, err := strconv.Atoi(ctx.DefaultQuery("fail", "1"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+
+	code, err := strconv.Atoi(ctx.DefaultQuery("code", strconv.Itoa(http.StatusServiceUnavailable)))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+
+	flaky.Lock()
+	key := ctx.Query("key")
+	flaky.attempts[key]++
+	attempt := flaky.attempts[key]
+	flaky.Unlock()
+
+	ctx.Header("X-Attempt", strconv.Itoa(attempt))
+	if attempt > fail {
+		Echo(ctx)
+		return
+	}
+
+	if _, reset := ctx.GetQuery("reset"); reset {
+		conn, _, err := ctx.Writer.Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+
+	if retryAfter := ctx.Query("retry_after"); retryAfter != "" {
+		ctx.Header("Retry-After", retryAfter)
+	}
+	ctx.JSON(code, gin.H{"result": "failed", "attempt": attempt})
+}
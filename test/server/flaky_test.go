@@ -0,0 +1,49 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlaky(t *testing.T) {
+	grids := []struct {
+		url        string
+		code       int
+		attempt    string
+		retryAfter string
+	}{
+		{url: "/flaky?key=TestFlaky&fail=2&retry_after=1", code: http.StatusServiceUnavailable, attempt: "1", retryAfter: "1"},
+		{url: "/flaky?key=TestFlaky&fail=2&code=429", code: http.StatusTooManyRequests, attempt: "2"},
+		{url: "/flaky?key=TestFlaky&fail=2", code: http.StatusOK, attempt: "3"},
+		{url: "/flaky?key=TestFlaky&fail=two", code: http.StatusBadRequest},
+	}
+
+	for _, grid := range grids {
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = httptest.NewRequest(http.MethodGet, grid.url, nil)
+		Flaky(ctx)
+
+		assert.Equal(t, grid.code, rec.Code, grid.url)
+		assert.Equal(t, grid.attempt, rec.Header().Get("X-Attempt"), grid.url)
+		assert.Equal(t, grid.retryAfter, rec.Header().Get("Retry-After"), grid.url)
+	}
+}
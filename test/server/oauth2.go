@@ -0,0 +1,234 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The client credentials accepted by OAuth2Token.
+const (
+	OAuth2ClientID     = "httpclient"
+	OAuth2ClientSecret = "secret"
+)
+
+var tokens = struct {
+	sync.Mutex
+	expiries map[string]time.Time      // By access token.
+	codes    map[string]*authorization // By authorization code.
+	refresh  map[string]bool           // The valid refresh tokens.
+	fetches  map[string]int            // By query parameter key.
+}{
+	expiries: make(map[string]time.Time),
+	codes:    make(map[string]*authorization),
+	refresh:  make(map[string]bool),
+	fetches:  make(map[string]int),
+}
+
+// authorization is an authorization code issued by OAuth2Authorize.
+type authorization struct {
+	redirectURI string
+	challenge   string
+	scope       string
+}
+
+// OAuth2Authorize is the authorization endpoint of a fake authorization
+// server, it approves the authorization code requests of the client
+// OAuth2ClientID with a S256 PKCE challenge and a loopback redirect URI,
+// RFC 8252. The user agent is redirected to the redirect URI with the
+// code, or with the error access_denied if the scope is "denied".
+func OAuth2Authorize(ctx *gin.Context) {
+	redirectURI, err := url.Parse(ctx.Query("redirect_uri"))
+	if err != nil || redirectURI.Scheme != "http" || net.ParseIP(redirectURI.Hostname()) == nil ||
+		!net.ParseIP(redirectURI.Hostname()).IsLoopback() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "invalid redirect_uri"})
+		return
+	}
+	if ctx.Query("client_id") != OAuth2ClientID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "invalid client_id"})
+		return
+	}
+
+	query := redirectURI.Query()
+	query.Set("state", ctx.Query("state"))
+	switch {
+	case ctx.Query("response_type") != "code":
+		query.Set("error", "unsupported_response_type")
+	case ctx.Query("code_challenge") == "" || ctx.Query("code_challenge_method") != "S256":
+		query.Set("error", "invalid_request")
+		query.Set("error_description", "S256 code challenge required")
+	case ctx.Query("scope") == "denied":
+		query.Set("error", "access_denied")
+	default:
+		code := randomToken()
+		tokens.Lock()
+		tokens.codes[code] = &authorization{
+			redirectURI: redirectURI.String(),
+			challenge:   ctx.Query("code_challenge"),
+			scope:       ctx.Query("scope"),
+		}
+		tokens.Unlock()
+		query.Set("code", code)
+	}
+
+	redirectURI.RawQuery = query.Encode()
+	ctx.Redirect(http.StatusFound, redirectURI.String())
+}
+
+// OAuth2TokenFetches returns the number of requests to OAuth2Token
+// with the query parameter key.
+func OAuth2TokenFetches(key string) int {
+	tokens.Lock()
+	defer tokens.Unlock()
+
+	return tokens.fetches[key]
+}
+
+// OAuth2Token is the token endpoint of a fake authorization server, it
+// issues access tokens for the client credentials, authorization code and
+// refresh token grants. The client OAuth2ClientID authenticates by HTTP
+// Basic authentication, or by its client_id as a public client except
+// for the client credentials grant. The query parameter expires_in
+// (default 3600) is the lifetime of the access tokens in seconds, and
+// the requests of every query parameter key are counted, see
+// OAuth2TokenFetches.
+func OAuth2Token(ctx *gin.Context) {
+	expiresIn, err := strconv.Atoi(ctx.DefaultQuery("expires_in", "3600"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	lifetime := time.Duration(expiresIn) * time.Second
+
+	tokens.Lock()
+	tokens.fetches[ctx.Query("key")]++
+	tokens.Unlock()
+
+	grantType := ctx.PostForm("grant_type")
+	id, secret, confidential := ctx.Request.BasicAuth()
+	if confidential && (id != OAuth2ClientID || secret != OAuth2ClientSecret) ||
+		!confidential && (ctx.PostForm("client_id") != OAuth2ClientID || grantType == "client_credentials") {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+
+	switch grantType {
+	case "client_credentials":
+		ctx.JSON(http.StatusOK, gin.H{
+			"access_token": issueToken(lifetime),
+			"token_type":   "bearer",
+			"expires_in":   expiresIn,
+			"scope":        ctx.PostForm("scope"),
+		})
+	case "authorization_code":
+		tokens.Lock()
+		code, ok := tokens.codes[ctx.PostForm("code")]
+		delete(tokens.codes, ctx.PostForm("code"))
+		tokens.Unlock()
+
+		verifier := sha256.Sum256([]byte(ctx.PostForm("code_verifier")))
+		if !ok || code.redirectURI != ctx.PostForm("redirect_uri") ||
+			code.challenge != base64.RawURLEncoding.EncodeToString(verifier[:]) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"access_token":  issueToken(lifetime),
+			"token_type":    "bearer",
+			"expires_in":    expiresIn,
+			"refresh_token": issueRefreshToken(),
+			"scope":         code.scope,
+		})
+	case "refresh_token":
+		// The refresh token is rotated, the used one is revoked.
+		tokens.Lock()
+		ok := tokens.refresh[ctx.PostForm("refresh_token")]
+		delete(tokens.refresh, ctx.PostForm("refresh_token"))
+		tokens.Unlock()
+
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"access_token":  issueToken(lifetime),
+			"token_type":    "bearer",
+			"expires_in":    expiresIn,
+			"refresh_token": issueRefreshToken(),
+		})
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+	}
+}
+
+// OAuth2Resource echoes the requests authorized by a valid access token
+// issued by OAuth2Token, and responds 401 to the others.
+func OAuth2Resource(ctx *gin.Context) {
+	token := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+
+	tokens.Lock()
+	expiry, ok := tokens.expiries[token]
+	tokens.Unlock()
+
+	if !ok || time.Now().After(expiry) {
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+	Echo(ctx)
+}
+
+// OAuth2Revoke revokes the access token of the form value token, RFC 7009.
+func OAuth2Revoke(ctx *gin.Context) {
+	tokens.Lock()
+	delete(tokens.expiries, ctx.PostForm("token"))
+	tokens.Unlock()
+
+	ctx.Status(http.StatusOK)
+}
+
+func issueToken(lifetime time.Duration) string {
+	token := randomToken()
+	tokens.Lock()
+	tokens.expiries[token] = time.Now().Add(lifetime)
+	tokens.Unlock()
+	return token
+}
+
+func issueRefreshToken() string {
+	token := randomToken()
+	tokens.Lock()
+	tokens.refresh[token] = true
+	tokens.Unlock()
+	return token
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
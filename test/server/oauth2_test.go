@@ -0,0 +1,220 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth2Token(t *testing.T) {
+	engine := NewEngine()
+
+	grids := []struct {
+		query     string
+		id        string
+		grantType string
+		code      int
+		error     string
+	}{
+		{id: OAuth2ClientID, grantType: "client_credentials", code: http.StatusOK},
+		{id: "other", grantType: "client_credentials", code: http.StatusUnauthorized, error: "invalid_client"},
+		{id: OAuth2ClientID, grantType: "password", code: http.StatusBadRequest, error: "unsupported_grant_type"},
+		{query: "?expires_in=soon", id: OAuth2ClientID, grantType: "client_credentials", code: http.StatusBadRequest, error: "invalid_request"},
+	}
+
+	for _, grid := range grids {
+		rec := httptest.NewRecorder()
+		form := url.Values{"grant_type": {grid.grantType}, "scope": {"read write"}}
+		req := httptest.NewRequest(http.MethodPost, "/oauth2/token"+grid.query, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(grid.id, OAuth2ClientSecret)
+		engine.ServeHTTP(rec, req)
+
+		var resp struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+			Scope       string `json:"scope"`
+			Error       string `json:"error"`
+		}
+		assert.Equal(t, grid.code, rec.Code, grid.id)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, grid.error, resp.Error)
+		if grid.code == http.StatusOK {
+			assert.NotEmpty(t, resp.AccessToken)
+			assert.Equal(t, 3600, resp.ExpiresIn)
+			assert.Equal(t, "read write", resp.Scope)
+		}
+	}
+}
+
+func TestOAuth2TokenFetches(t *testing.T) {
+	engine := NewEngine()
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/oauth2/token?key=TestOAuth2TokenFetches", strings.NewReader("grant_type=client_credentials"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(OAuth2ClientID, OAuth2ClientSecret)
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, 2, OAuth2TokenFetches("TestOAuth2TokenFetches"))
+}
+
+func TestOAuth2Resource(t *testing.T) {
+	engine := NewEngine()
+	valid := issueToken(time.Hour)
+	expired := issueToken(0)
+
+	resource := func(token string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/oauth2/resource", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		engine.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, resource(valid))
+	assert.Equal(t, http.StatusUnauthorized, resource(expired))
+	assert.Equal(t, http.StatusUnauthorized, resource("unknown"))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/revoke", strings.NewReader("token="+valid))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusUnauthorized, resource(valid))
+}
+
+func TestOAuth2Authorize(t *testing.T) {
+	engine := NewEngine()
+
+	grids := []struct {
+		query    url.Values
+		code     int
+		location url.Values
+	}{
+		{
+			query: url.Values{"redirect_uri": {"http://example.com/callback"}, "client_id": {OAuth2ClientID}},
+			code:  http.StatusBadRequest,
+		},
+		{
+			query: url.Values{"redirect_uri": {"http://127.0.0.1:8000/callback"}, "client_id": {"other"}},
+			code:  http.StatusBadRequest,
+		},
+		{
+			query: url.Values{
+				"redirect_uri": {"http://127.0.0.1:8000/callback"}, "client_id": {OAuth2ClientID},
+				"response_type": {"token"}, "state": {"xyz"},
+			},
+			code:     http.StatusFound,
+			location: url.Values{"error": {"unsupported_response_type"}, "state": {"xyz"}},
+		},
+		{
+			query: url.Values{
+				"redirect_uri": {"http://127.0.0.1:8000/callback"}, "client_id": {OAuth2ClientID},
+				"response_type": {"code"}, "state": {"xyz"}, "code_challenge": {"abc"}, "code_challenge_method": {"plain"},
+			},
+			code:     http.StatusFound,
+			location: url.Values{"error": {"invalid_request"}, "error_description": {"S256 code challenge required"}, "state": {"xyz"}},
+		},
+		{
+			query: url.Values{
+				"redirect_uri": {"http://127.0.0.1:8000/callback"}, "client_id": {OAuth2ClientID},
+				"response_type": {"code"}, "state": {"xyz"}, "code_challenge": {"abc"}, "code_challenge_method": {"S256"},
+				"scope": {"denied"},
+			},
+			code:     http.StatusFound,
+			location: url.Values{"error": {"access_denied"}, "state": {"xyz"}},
+		},
+	}
+
+	for _, grid := range grids {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/oauth2/authorize?"+grid.query.Encode(), nil))
+
+		assert.Equal(t, grid.code, rec.Code, grid.query.Encode())
+		if grid.location != nil {
+			location, err := url.Parse(rec.Header().Get("Location"))
+			if assert.NoError(t, err) {
+				assert.Equal(t, "127.0.0.1:8000", location.Host)
+				assert.Equal(t, grid.location, location.Query())
+			}
+		}
+	}
+}
+
+func TestOAuth2Token_AuthorizationCode(t *testing.T) {
+	engine := NewEngine()
+	verifier := "dBjftJeZ4CVP-mJ92K9qP2sUe4yOkZzDrJ6g3XHWQ8U"
+	challenge := sha256.Sum256([]byte(verifier))
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/oauth2/authorize?"+url.Values{
+		"redirect_uri": {"http://127.0.0.1:8000/callback"}, "client_id": {OAuth2ClientID},
+		"response_type": {"code"}, "state": {"xyz"}, "scope": {"read"},
+		"code_challenge": {base64.RawURLEncoding.EncodeToString(challenge[:])}, "code_challenge_method": {"S256"},
+	}.Encode(), nil))
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	code := location.Query().Get("code")
+	assert.NotEmpty(t, code)
+
+	token := func(form url.Values) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		engine.ServeHTTP(rec, req)
+
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec.Code, resp
+	}
+
+	exchange := url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {OAuth2ClientID}, "code": {code},
+		"redirect_uri": {"http://127.0.0.1:8000/callback"}, "code_verifier": {"wrong"},
+	}
+	status, resp := token(exchange)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", resp["error"])
+
+	// A code is used once, even by a failed exchange.
+	exchange.Set("code_verifier", verifier)
+	status, _ = token(exchange)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, resp = token(url.Values{"grant_type": {"client_credentials"}, "client_id": {OAuth2ClientID}})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "invalid_client", resp["error"])
+
+	refresh := issueRefreshToken()
+	form := url.Values{"grant_type": {"refresh_token"}, "client_id": {OAuth2ClientID}, "refresh_token": {refresh}}
+	status, resp = token(form)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp["access_token"])
+	assert.NotEqual(t, refresh, resp["refresh_token"])
+
+	// The refresh token is rotated.
+	status, resp = token(form)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", resp["error"])
+}
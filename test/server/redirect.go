@@ -0,0 +1,34 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Redirect redirects to the query parameter to (default /echo)
+// with the status code of the query parameter code (default 307).
+func Redirect(ctx *gin.Context) {
+	code, err := strconv.Atoi(ctx.DefaultQuery("code", strconv.Itoa(http.StatusTemporaryRedirect)))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+
+	ctx.Redirect(code, ctx.DefaultQuery("to", "/echo"))
+}
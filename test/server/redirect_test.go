@@ -0,0 +1,47 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirect(t *testing.T) {
+	grids := []struct {
+		url      string
+		code     int
+		location string
+	}{
+		{url: "/redirect", code: http.StatusTemporaryRedirect, location: "/echo"},
+		{url: "/redirect?code=302&to=/users", code: http.StatusFound, location: "/users"},
+		{url: "/redirect?code=found", code: http.StatusBadRequest},
+	}
+
+	for _, grid := range grids {
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = httptest.NewRequest(http.MethodPost, grid.url, nil)
+		Redirect(ctx)
+		ctx.Writer.WriteHeaderNow()
+
+		assert.Equal(t, grid.code, rec.Code)
+		assert.Equal(t, grid.location, rec.Header().Get("Location"))
+	}
+}
@@ -23,7 +23,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Server(ctx context.Context, cancel context.CancelFunc, address string) {
+// NewEngine returns the engine serving the mock API,
+// it can also be used with httptest.NewServer.
+func NewEngine() *gin.Engine {
 	engine := gin.Default()
 
 	users := NewMockUsers()
@@ -32,6 +34,30 @@ func Server(ctx context.Context, cancel context.CancelFunc, address string) {
 	group.GET("", users.GetUsers)
 	group.POST("", users.AddUser)
 
+	engine.Any("/echo", Echo)
+	engine.Handle("PROPFIND", "/echo", Echo)
+	engine.GET("/stream", Stream)
+	engine.Any("/redirect", Redirect)
+	engine.POST("/upload", Upload)
+	engine.Any("/flaky", Flaky)
+	engine.POST("/login", Login)
+	engine.GET("/session", Session)
+	engine.POST("/logout", Logout)
+	engine.Any("/digest", Digest)
+	engine.Any("/signature", Signature)
+
+	oauth2 := engine.Group("/oauth2")
+	oauth2.GET("/authorize", OAuth2Authorize)
+	oauth2.POST("/token", OAuth2Token)
+	oauth2.Any("/resource", OAuth2Resource)
+	oauth2.POST("/revoke", OAuth2Revoke)
+
+	return engine
+}
+
+func Server(ctx context.Context, cancel context.CancelFunc, address string) {
+	engine := NewEngine()
+
 	ops := []fishserver.Option{
 		fishserver.WithCancelFunc(cancel),
 		fishserver.WithContext(ctx),
@@ -0,0 +1,55 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookie is the name of the cookie set by Login.
+const SessionCookie = "session"
+
+// Login sets the session cookie to the form value username,
+// it stands for the login of an admin portal.
+func Login(ctx *gin.Context) {
+	username := ctx.PostForm("username")
+	if username == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": "missing username"})
+		return
+	}
+
+	ctx.SetCookie(SessionCookie, username, 0, "/", "", false, true)
+	ctx.JSON(http.StatusOK, gin.H{"result": "login successfully"})
+}
+
+// Session responds with the username of the session cookie,
+// or 401 if there is no session.
+func Session(ctx *gin.Context) {
+	username, err := ctx.Cookie(SessionCookie)
+	if err != nil || username == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"result": "no session"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"username": username})
+}
+
+// Logout removes the session cookie.
+func Logout(ctx *gin.Context) {
+	ctx.SetCookie(SessionCookie, "", -1, "/", "", false, true)
+	ctx.JSON(http.StatusOK, gin.H{"result": "logout successfully"})
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	engine := NewEngine()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(url.Values{"username": {"kitty"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	cookies := rec.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, SessionCookie, cookies[0].Name)
+		assert.Equal(t, "kitty", cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+	}
+
+	grids := []struct {
+		cookie   *http.Cookie
+		code     int
+		expected string
+	}{
+		{cookie: cookies[0], code: http.StatusOK, expected: `{"username":"kitty"}`},
+		{code: http.StatusUnauthorized, expected: `{"result":"no session"}`},
+	}
+
+	for _, grid := range grids {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/session", nil)
+		if grid.cookie != nil {
+			req.AddCookie(grid.cookie)
+		}
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, grid.code, rec.Code)
+		assert.JSONEq(t, grid.expected, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/logout", nil))
+	cookies = rec.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, -1, cookies[0].MaxAge)
+	}
+}
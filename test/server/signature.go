@@ -0,0 +1,67 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/coolstina/httpclient/httpsig"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The keys accepted by Signature.
+const (
+	SignatureHMACKeyID    = "test-hmac"
+	SignatureHMACSecret   = "secret"
+	SignatureEd25519KeyID = "test-ed25519"
+)
+
+// SignatureEd25519Key is the private key of SignatureEd25519KeyID.
+var SignatureEd25519Key = ed25519.NewKeyFromSeed([]byte("httpclient test ed25519 key seed"))
+
+var signatureVerifier = &httpsig.Verifier{
+	Keys: func(keyID string) (httpsig.Algorithm, error) {
+		switch keyID {
+		case SignatureHMACKeyID:
+			return httpsig.HMACSHA256([]byte(SignatureHMACSecret)), nil
+		case SignatureEd25519KeyID:
+			return httpsig.Ed25519Public(SignatureEd25519Key.Public().(ed25519.PublicKey)), nil
+		}
+		return nil, errors.New("unknown key " + keyID)
+	},
+	Required: []string{"@method", "@target-uri"},
+	MaxAge:   5 * time.Minute,
+}
+
+// Signature echoes the requests signed by the HTTP message signatures of
+// RFC 9421 with one of the test keys, and responds 401 to the others. The
+// signatures must cover the method and the target URI, and the body if
+// it is not empty, by the content-digest header.
+func Signature(ctx *gin.Context) {
+	verifier := *signatureVerifier
+	if ctx.Request.ContentLength != 0 {
+		verifier.Required = append(verifier.Required[:len(verifier.Required):len(verifier.Required)], "content-digest")
+	}
+
+	if err := verifier.Verify(ctx.Request, time.Now()); err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"result": err.Error()})
+		return
+	}
+	Echo(ctx)
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coolstina/httpclient/httpsig"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	engine := NewEngine()
+
+	hmacSigner := &httpsig.Signer{
+		KeyID:      SignatureHMACKeyID,
+		Algorithm:  httpsig.HMACSHA256([]byte(SignatureHMACSecret)),
+		Components: []string{"@method", "@target-uri"},
+	}
+	ed25519Signer := &httpsig.Signer{
+		KeyID:      SignatureEd25519KeyID,
+		Algorithm:  httpsig.Ed25519(SignatureEd25519Key),
+		Components: []string{"@method", "@target-uri", "content-digest"},
+	}
+
+	grids := []struct {
+		signer *httpsig.Signer
+		method string
+		body   string
+		code   int
+	}{
+		{signer: nil, method: http.MethodGet, code: http.StatusUnauthorized},
+		{signer: hmacSigner, method: http.MethodGet, code: http.StatusOK},
+		{signer: hmacSigner, method: http.MethodPost, body: "body", code: http.StatusUnauthorized},
+		{signer: ed25519Signer, method: http.MethodPost, body: "body", code: http.StatusOK},
+		{signer: &httpsig.Signer{KeyID: "unknown", Algorithm: hmacSigner.Algorithm, Components: hmacSigner.Components}, method: http.MethodGet, code: http.StatusUnauthorized},
+	}
+
+	for _, grid := range grids {
+		req, err := http.NewRequest(grid.method, "http://example.com/signature?id=1", strings.NewReader(grid.body))
+		assert.NoError(t, err)
+		if grid.signer != nil {
+			assert.NoError(t, grid.signer.Sign(req, time.Now()))
+		}
+
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		assert.Equal(t, grid.code, rec.Code, rec.Body.String())
+	}
+}
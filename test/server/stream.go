@@ -0,0 +1,52 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stream writes the query parameter chunks (default 10) lines to the body,
+// flushing each one, and waits interval milliseconds (default 10)
+// before each line.
+func Stream(ctx *gin.Context) {
+	chunks, err := strconv.Atoi(ctx.DefaultQuery("chunks", "10"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+
+	interval, err := strconv.Atoi(ctx.DefaultQuery("interval", "10"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	for i := 0; i < chunks; i++ {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-time.After(time.Duration(interval) * time.Millisecond):
+		}
+
+		_, _ = ctx.Writer.WriteString(strconv.Itoa(i) + "\n")
+		ctx.Writer.Flush()
+	}
+}
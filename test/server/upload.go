@@ -0,0 +1,74 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadPart describes a part received by the Upload handler.
+type UploadPart struct {
+	Name        string              `json:"name"`
+	Filename    string              `json:"filename"`
+	ContentType string              `json:"content_type"`
+	Header      map[string][]string `json:"header"`
+	Size        int64               `json:"size"`
+	SHA256      string              `json:"sha256"`
+}
+
+// Upload streams a multipart/form-data body and responds with its parts,
+// the content of a part is summarized by its size and SHA-256 digest.
+func Upload(ctx *gin.Context) {
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+		return
+	}
+
+	parts := make([]UploadPart, 0)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+			return
+		}
+
+		hash := sha256.New()
+		size, err := io.Copy(hash, part)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"result": err.Error()})
+			return
+		}
+
+		parts = append(parts, UploadPart{
+			Name:        part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Header:      part.Header,
+			Size:        size,
+			SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, parts)
+}
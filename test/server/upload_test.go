@@ -0,0 +1,64 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	assert.NoError(t, writer.WriteField("username", "helloshaohua"))
+	file, err := writer.CreateFormFile("avatar", "avatar.png")
+	assert.NoError(t, err)
+	_, err = file.Write([]byte("png"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/upload", &body)
+	ctx.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	Upload(ctx)
+
+	var parts []UploadPart
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &parts))
+	if assert.Len(t, parts, 2) {
+		assert.Equal(t, "username", parts[0].Name)
+		assert.Equal(t, int64(12), parts[0].Size)
+		assert.Equal(t, "avatar.png", parts[1].Filename)
+		assert.Equal(t, "application/octet-stream", parts[1].ContentType)
+		assert.Len(t, parts[1].SHA256, 64)
+	}
+}
+
+func TestUpload_BadRequest(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/upload", nil)
+	Upload(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,245 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryDelta is how long before its expiry a token is fetched again,
+// so a request never reaches the server with an expired token.
+const tokenExpiryDelta = 10 * time.Second
+
+// Token is an access token, e.g. of OAuth2 or of the sessions of an API.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"` // Zero if the token never expires.
+}
+
+// Valid reports whether the token is set and does not expire
+// within the next 10 seconds.
+func (token *Token) Valid() bool {
+	return token.valid(time.Now())
+}
+
+func (token *Token) valid(now time.Time) bool {
+	return token != nil && token.AccessToken != "" &&
+		(token.Expiry.IsZero() || now.Add(tokenExpiryDelta).Before(token.Expiry))
+}
+
+// authorization returns the Authorization header value of the token.
+func (token *Token) authorization() string {
+	if token.TokenType == "" || strings.EqualFold(token.TokenType, "bearer") {
+		return "Bearer " + token.AccessToken
+	}
+	return token.TokenType + " " + token.AccessToken
+}
+
+// tokenCache caches a token, the concurrent fetches of a new one share
+// a single flight.
+type tokenCache struct {
+	token  *Token
+	flight *tokenFlight
+	mux    sync.Mutex
+}
+
+type tokenFlight struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// get returns the cached token if it is valid and not stale, or fetch
+// a new one. A caller whose request was rejected with the token stale
+// passes it, so it is fetched again unless another caller already did.
+func (cache *tokenCache) get(ctx context.Context, stale *Token, fetch func(ctx context.Context) (*Token, error)) (*Token, error) {
+	for {
+		cache.mux.Lock()
+		if token := cache.token; token != stale && token.valid(time.Now()) {
+			cache.mux.Unlock()
+			return token, nil
+		}
+
+		if flight := cache.flight; flight != nil {
+			cache.mux.Unlock()
+
+			select {
+			case <-flight.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			// The context of the caller who fetched is not the one of this
+			// caller, which fetches again if the other was cancelled.
+			if errors.Is(flight.err, context.Canceled) || errors.Is(flight.err, context.DeadlineExceeded) {
+				continue
+			}
+			return flight.token, flight.err
+		}
+
+		flight := &tokenFlight{done: make(chan struct{})}
+		cache.flight = flight
+		cache.mux.Unlock()
+
+		flight.token, flight.err = fetch(ctx)
+		if flight.token == nil && flight.err == nil {
+			flight.err = errors.New("httpclient: no token")
+		}
+
+		cache.mux.Lock()
+		cache.flight = nil
+		if flight.err == nil {
+			cache.token = flight.token
+		}
+		cache.mux.Unlock()
+		close(flight.done)
+
+		return flight.token, flight.err
+	}
+}
+
+// tokenAuth authenticates requests by the cached tokens of fetch,
+// which is passed the token rejected by the server, if any.
+type tokenAuth struct {
+	cache     tokenCache
+	fetch     func(ctx context.Context, send Handler, stale *Token) (*Token, error)
+	maxBuffer int64 // Of the bodies which are not rewindable.
+}
+
+// do execute req by send with the cached token. If the server responds
+// 401, the token may have been revoked, so a new one is fetched and req
+// sent again once with it, when its body is rewindable or buffered.
+func (auth *tokenAuth) do(send Handler, req *http.Request) (*http.Response, error) {
+	req, err := bufferBody(req, auth.maxBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := auth.cache.get(req.Context(), nil, func(ctx context.Context) (*Token, error) {
+		return auth.fetch(ctx, send, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := send(authorizeToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The token is renewed even if req cannot be sent again,
+	// so the next requests do not send it.
+	fresh, err := auth.cache.get(req.Context(), token, func(ctx context.Context) (*Token, error) {
+		return auth.fetch(ctx, send, token)
+	})
+	if err != nil || !rewindable(req) {
+		return resp, nil
+	}
+	next, err := rewind(req)
+	if err != nil {
+		return resp, nil
+	}
+
+	drainBody(resp)
+
+	return send(authorizeToken(next, fresh))
+}
+
+func authorizeToken(req *http.Request, token *Token) *http.Request {
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", token.authorization())
+	return authorized
+}
+
+// bufferBody returns req with its body buffered, so it can be sent again,
+// if it is not rewindable and its size is at most limit. A larger body is
+// sent as is.
+func bufferBody(req *http.Request, limit int64) (*http.Request, error) {
+	if limit <= 0 || rewindable(req) || req.ContentLength > limit {
+		return req, nil
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		req.Body.Close()
+		return nil, err
+	}
+
+	buffered := req.Clone(req.Context())
+	if int64(len(buf)) > limit {
+		buffered.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(buf), req.Body), Closer: req.Body}
+		return buffered, nil
+	}
+
+	req.Body.Close()
+	buffered.ContentLength = int64(len(buf))
+	buffered.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	buffered.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+	}
+	return buffered, nil
+}
+
+// TokenSource returns the tokens authenticating requests, see TokenAuth.
+type TokenSource interface {
+	// Token returns a new token. It is called when there is no valid token,
+	// including after the server rejected the last one, so a rejected
+	// token must not be returned again.
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc is an adapter to use a function as a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token implements the TokenSource interface.
+func (fn TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return fn(ctx)
+}
+
+// TokenAuth returns a middleware setting the Authorization header of the
+// requests to the tokens of source, e.g. the session tokens of an API.
+//
+// A token is cached until it expires, concurrent requests share a single
+// call of source. If the server responds 401, the token is invalidated and
+// the request sent again once with a new one. A body which is not rewindable
+// is buffered to be sent again, up to maxBuffer bytes, a larger one is only
+// sent once. Like the credentials of a client, the token is not sent to
+// another origin after a redirect.
+func TokenAuth(source TokenSource, maxBuffer int64) Middleware {
+	auth := &tokenAuth{
+		fetch: func(ctx context.Context, _ Handler, _ *Token) (*Token, error) {
+			return source.Token(ctx)
+		},
+		maxBuffer: maxBuffer,
+	}
+	creds := &credentials{header: "Authorization", auth: auth}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			req = req.WithContext(context.WithValue(req.Context(), credentialsKey{}, creds))
+			return auth.do(next, req)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken_Valid(t *testing.T) {
+	now := time.Now()
+
+	grids := []struct {
+		token    *Token
+		expected bool
+	}{
+		{token: nil, expected: false},
+		{token: &Token{}, expected: false},
+		{token: &Token{AccessToken: "token"}, expected: true},
+		{token: &Token{AccessToken: "token", Expiry: now.Add(time.Minute)}, expected: true},
+		{token: &Token{AccessToken: "token", Expiry: now.Add(5 * time.Second)}, expected: false},
+		{token: &Token{AccessToken: "token", Expiry: now.Add(-time.Minute)}, expected: false},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, grid.token.valid(now), "%+v", grid.token)
+	}
+}
+
+func TestToken_Authorization(t *testing.T) {
+	grids := []struct {
+		tokenType string
+		expected  string
+	}{
+		{tokenType: "", expected: "Bearer token"},
+		{tokenType: "bearer", expected: "Bearer token"},
+		{tokenType: "MAC", expected: "MAC token"},
+	}
+
+	for _, grid := range grids {
+		token := &Token{AccessToken: "token", TokenType: grid.tokenType}
+		assert.Equal(t, grid.expected, token.authorization())
+	}
+}
+
+func TestTokenCache_Get(t *testing.T) {
+	var cache tokenCache
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (*Token, error) {
+		<-release
+		return &Token{AccessToken: string(rune('a' + atomic.AddInt32(&fetches, 1) - 1))}, nil
+	}
+
+	// Concurrent callers share a single fetch.
+	var wg sync.WaitGroup
+	tokens := make([]*Token, 20)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = cache.get(context.Background(), nil, fetch)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), fetches)
+	for _, token := range tokens {
+		assert.Same(t, tokens[0], token)
+	}
+
+	// A stale token is fetched again, once.
+	fresh, err := cache.get(context.Background(), tokens[0], fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", fresh.AccessToken)
+
+	again, err := cache.get(context.Background(), tokens[0], fetch)
+	assert.NoError(t, err)
+	assert.Same(t, fresh, again)
+	assert.Equal(t, int32(2), fetches)
+
+	// A failed fetch is not cached.
+	failure := errors.New("failure")
+	_, err = cache.get(context.Background(), fresh, func(ctx context.Context) (*Token, error) {
+		return nil, failure
+	})
+	assert.Equal(t, failure, err)
+	assert.Same(t, fresh, cache.token)
+}
+
+func TestTokenCache_Get_Canceled(t *testing.T) {
+	var cache tokenCache
+	started := make(chan struct{})
+
+	// The caller who fetches is cancelled,
+	// so the waiting caller fetches again.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _ = cache.get(ctx, nil, func(ctx context.Context) (*Token, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	}()
+	<-started
+
+	done := make(chan *Token)
+	go func() {
+		token, _ := cache.get(context.Background(), nil, func(ctx context.Context) (*Token, error) {
+			return &Token{AccessToken: "token"}, nil
+		})
+		done <- token
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case token := <-done:
+		assert.Equal(t, "token", token.AccessToken)
+	case <-time.After(time.Second):
+		t.Fatal("waiting caller did not fetch")
+	}
+}
+
+func TestBufferBody(t *testing.T) {
+	grids := []struct {
+		body       io.Reader
+		limit      int64
+		rewindable bool
+	}{
+		{body: nil, limit: 4, rewindable: true},
+		{body: strings.NewReader("body"), limit: 0, rewindable: true},
+		{body: ioutil.NopCloser(strings.NewReader("body")), limit: 4, rewindable: true},
+		{body: ioutil.NopCloser(strings.NewReader("body")), limit: 3, rewindable: false},
+		{body: ioutil.NopCloser(strings.NewReader("body")), limit: 0, rewindable: false},
+	}
+
+	for _, grid := range grids {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com", grid.body)
+		assert.NoError(t, err)
+
+		buffered, err := bufferBody(req, grid.limit)
+		assert.NoError(t, err)
+		assert.Equal(t, grid.rewindable, rewindable(buffered), "limit: %d", grid.limit)
+		if grid.body != nil {
+			body, err := ioutil.ReadAll(buffered.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "body", string(body))
+		}
+	}
+}
+
+// tokenServer echoes the body of the requests authorized by the
+// current token, which is rotated by rotate.
+func tokenServer() (ts *httptest.Server, rotate func()) {
+	var current atomic.Value
+	current.Store("Bearer 1")
+
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != current.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+
+	var n int32 = 1
+	return ts, func() {
+		current.Store("Bearer " + string(rune('0'+atomic.AddInt32(&n, 1))))
+	}
+}
+
+// text returns the body of resp.
+func (suite *RequestSuite) text(resp *Response) string {
+	body, err := resp.String()
+	assert.NoError(suite.T(), err)
+	return body
+}
+
+// countingSource returns the tokens "1", "2", etc.
+func countingSource(calls *int32) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return &Token{AccessToken: string(rune('0' + atomic.AddInt32(calls, 1)))}, nil
+	})
+}
+
+func (suite *RequestSuite) Test_TokenAuth() {
+	ts, _ := tokenServer()
+	defer ts.Close()
+
+	var calls int32
+	client := New(WithBaseURL(ts.URL)).Use(TokenAuth(countingSource(&calls), 0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Post("/").BodyWithJSON("body").Do()
+			if assert.NoError(suite.T(), err) {
+				assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+				assert.Equal(suite.T(), "body", suite.text(resp))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(suite.T(), int32(1), calls)
+}
+
+func (suite *RequestSuite) Test_TokenAuth_Rejected() {
+	ts, rotate := tokenServer()
+	defer ts.Close()
+
+	var calls int32
+	client := New(WithBaseURL(ts.URL)).Use(TokenAuth(countingSource(&calls), 1<<10))
+	resp, err := client.Get("/").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	// The body which is not rewindable is buffered, to be sent again
+	// with the new token.
+	rotate()
+	resp, err = client.Post("/").Body(ioutil.NopCloser(strings.NewReader("body"))).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "body", suite.text(resp))
+	assert.Equal(suite.T(), int32(2), calls)
+
+	// A token which is rejected again is not replayed twice.
+	_, err = client.Get("/").Do()
+	assert.NoError(suite.T(), err)
+	rotate()
+	rotate()
+	resp, err = client.Get("/").Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(suite.T(), int32(3), calls)
+}
+
+func (suite *RequestSuite) Test_TokenAuth_BodyTooLarge() {
+	ts, rotate := tokenServer()
+	defer ts.Close()
+
+	var calls int32
+	client := New(WithBaseURL(ts.URL)).Use(TokenAuth(countingSource(&calls), 3))
+	_, err := client.Get("/").Do()
+	assert.NoError(suite.T(), err)
+
+	// The body is larger than the buffer, so the 401 is returned,
+	// but the token is renewed for the next requests.
+	rotate()
+	resp, err := client.Post("/").Body(ioutil.NopCloser(strings.NewReader("body"))).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(suite.T(), int32(2), calls)
+
+	resp, err = client.Post("/").Body(ioutil.NopCloser(strings.NewReader("body"))).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "body", suite.text(resp))
+	assert.Equal(suite.T(), int32(2), calls)
+}
+
+func (suite *RequestSuite) Test_TokenAuth_Error() {
+	failure := errors.New("failure")
+	source := TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return nil, failure
+	})
+
+	_, err := New().Use(TokenAuth(source, 0)).Get(suite.server.URL + "/echo").Do()
+	assert.Equal(suite.T(), failure, err)
+
+	source = func(ctx context.Context) (*Token, error) {
+		return nil, nil
+	}
+	_, err = New().Use(TokenAuth(source, 0)).Get(suite.server.URL + "/echo").Do()
+	assert.EqualError(suite.T(), err, "httpclient: no token")
+}
+
+func (suite *RequestSuite) Test_TokenAuth_Redirect() {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer other.Close()
+	ts := httptest.NewServer(http.RedirectHandler(other.URL, http.StatusFound))
+	defer ts.Close()
+
+	var calls int32
+	resp, err := New().Use(TokenAuth(countingSource(&calls), 0)).Get(ts.URL).Do()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "", suite.text(resp))
+}
@@ -36,3 +36,11 @@ func FixedURL(rawurl string) (*url.URL, error) {
 
 	return parse, nil
 }
+
+// joinURL join rawurl to the base URL, an absolute rawurl is returned as is.
+func joinURL(base, rawurl string) string {
+	if base == "" || strings.HasPrefix(rawurl, "http://") || strings.HasPrefix(rawurl, "https://") {
+		return rawurl
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimRight(base, "/"), strings.TrimLeft(rawurl, "/"))
+}
@@ -75,3 +75,36 @@ func (suite *URLSuite) Test_FixedURL() {
 		assert.Equal(suite.T(), grid.expected, actual.String())
 	}
 }
+
+func (suite *URLSuite) Test_joinURL() {
+	grids := []struct {
+		base     string
+		url      string
+		expected string
+	}{
+		{
+			base:     "",
+			url:      "/users",
+			expected: "/users",
+		},
+		{
+			base:     "http://localhost:8000",
+			url:      "/users",
+			expected: "http://localhost:8000/users",
+		},
+		{
+			base:     "http://localhost:8000/api/",
+			url:      "users?id=1",
+			expected: "http://localhost:8000/api/users?id=1",
+		},
+		{
+			base:     "http://localhost:8000/api",
+			url:      "https://www.baidu.com/?q=hello+world",
+			expected: "https://www.baidu.com/?q=hello+world",
+		},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(suite.T(), grid.expected, joinURL(grid.base, grid.url))
+	}
+}